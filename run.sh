@@ -13,4 +13,4 @@ echo ""
 
 # Run the server
 cd "$(dirname "$0")"
-go run src/server.go src/auth.go
\ No newline at end of file
+go run ./src
\ No newline at end of file
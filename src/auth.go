@@ -1,9 +1,16 @@
 package main
 
 import (
+	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -12,9 +19,9 @@ import (
 // DemoUser represents a user in our demo system
 // Supports both new simplified format (IsAutojoinAdmin) and legacy format (Role, Groups)
 type DemoUser struct {
-	ID       string      `json:"id"`
-	Email    string      `json:"email"`
-	Password string      `json:"-"` // Never include password in JSON
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Password string `json:"-"` // Never include password in JSON
 
 	// New simplified field (preferred)
 	IsAutojoinAdmin bool `json:"isAutojoinAdmin"`
@@ -22,6 +29,16 @@ type DemoUser struct {
 	// Legacy fields (deprecated but still supported for backward compatibility)
 	Role   string      `json:"role"`
 	Groups []UserGroup `json:"groups"`
+
+	// ID (jti) of the session token the user was loaded from, if any
+	SessionID string `json:"-"`
+
+	// ID of the admin impersonating this user, if any
+	ImpersonatedBy string `json:"-"`
+
+	// When the user was soft-deleted; deleted users can't log in and are
+	// hidden from listings but kept for history
+	DeletedAt *time.Time `json:"-"`
 }
 
 // UserGroup represents a group membership
@@ -39,14 +56,82 @@ type LoginRequest struct {
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Success bool     `json:"success"`
-	User    DemoUser `json:"user,omitempty"`
-	Error   string   `json:"error,omitempty"`
+	Success bool        `json:"success"`
+	User    interface{} `json:"user,omitempty"`
+	Token   string      `json:"token,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// UserView is the user as returned by the API, without legacy fields
+type UserView struct {
+	ID              string `json:"id"`
+	Email           string `json:"email"`
+	IsAutojoinAdmin bool   `json:"isAutojoinAdmin"`
+}
+
+// PublicUser is every user field safe to return from the API. Responses
+// serialize this rather than DemoUser so internal fields such as the
+// password hash can't leak.
+type PublicUser struct {
+	UserView
+	Role   string      `json:"role"`
+	Groups []UserGroup `json:"groups"`
+}
+
+// Convert a user to its public representation
+func toPublicUser(user DemoUser) PublicUser {
+	return PublicUser{
+		UserView: UserView{
+			ID:              user.ID,
+			Email:           user.Email,
+			IsAutojoinAdmin: user.IsAutojoinAdmin,
+		},
+		Role:   user.Role,
+		Groups: user.Groups,
+	}
+}
+
+// Build the API view of a user, optionally including legacy fields
+func userView(user DemoUser, legacy bool) interface{} {
+	public := toPublicUser(user)
+	if !legacy {
+		return public.UserView
+	}
+	return public
+}
+
+// Whether the client wants legacy user fields. Clients opt out with
+// ?legacy=false or an Accept-Version header of 2 or later.
+func wantsLegacyFields(c *gin.Context) bool {
+	if c.Query("legacy") == "false" {
+		return false
+	}
+	if version, err := strconv.Atoi(strings.TrimPrefix(c.GetHeader("Accept-Version"), "v")); err == nil && version >= 2 {
+		return false
+	}
+	return true
+}
+
+// GroupSummary is a normalized type/name pair for a user's group
+type GroupSummary struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// MeResponse is the envelope returned by /api/auth/me
+type MeResponse struct {
+	User           interface{}    `json:"user"`
+	GroupCount     int            `json:"groupCount"`
+	HasAdminScopes bool           `json:"hasAdminScopes"`
+	Groups         []GroupSummary `json:"groups"`
+	ImpersonatedBy string         `json:"impersonatedBy,omitempty"`
 }
 
 // Demo users database (in a real app, this would be in a database)
 // Demo users with new simplified format (IsAutojoinAdmin)
 // Legacy fields (Role, Groups) are also included for backward compatibility demo
+// Guarded by demoUsersMu once the server is running
+var demoUsersMu sync.RWMutex
 var demoUsers = []DemoUser{
 	{
 		ID:              "user-1",
@@ -71,7 +156,211 @@ var demoUsers = []DemoUser{
 	},
 }
 
-const jwtSecret = "demo-secret-key"
+// demoUserSeed is an entry in a DEMO_USERS_FILE, with a plaintext password
+type demoUserSeed struct {
+	ID              string      `json:"id"`
+	Email           string      `json:"email"`
+	Password        string      `json:"password"`
+	IsAutojoinAdmin bool        `json:"isAutojoinAdmin"`
+	Role            string      `json:"role"`
+	Groups          []UserGroup `json:"groups"`
+}
+
+// Load demo users from a JSON file, hashing their plaintext passwords
+func loadDemoUsersFile(path string) ([]DemoUser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var seeds []demoUserSeed
+	if err := json.Unmarshal(data, &seeds); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("%s contains no users", path)
+	}
+
+	users := make([]DemoUser, 0, len(seeds))
+	for i, seed := range seeds {
+		if seed.Email == "" || seed.Password == "" {
+			return nil, fmt.Errorf("user %d in %s is missing email or password", i, path)
+		}
+		if err := validatePassword(seed.Password); err != nil {
+			return nil, fmt.Errorf("user %s in %s: %w", seed.Email, path, err)
+		}
+
+		id := seed.ID
+		if id == "" {
+			id = fmt.Sprintf("user-%d", i+1)
+		}
+		users = append(users, DemoUser{
+			ID:              id,
+			Email:           normalizeEmail(seed.Email),
+			Password:        hashPassword(seed.Password),
+			IsAutojoinAdmin: seed.IsAutojoinAdmin,
+			Role:            seed.Role,
+			Groups:          seed.Groups,
+		})
+	}
+	return users, nil
+}
+
+// Secret used outside release mode when SESSION_JWT_SECRET is unset
+const demoJWTSecret = "demo-secret-key"
+
+// Key ID and key used to sign new session JWTs: the RSA private key for
+// RS* algorithms, otherwise the current keyring secret or, without a
+// keyring, the single secret with no kid
+func sessionSigningKey() (string, interface{}, error) {
+	if sessionAlgIsRSA(config.SessionJWTAlg) {
+		key, err := sessionRSAKey()
+		return "", key, err
+	}
+	if secret, ok := config.SessionJWTKeys[config.SessionJWTCurrentKID]; ok {
+		return config.SessionJWTCurrentKID, []byte(secret), nil
+	}
+	return "", sessionSecret(), nil
+}
+
+// Pick the key for verifying a session JWT: the RSA public key for RS*
+// algorithms, otherwise the secret named by its kid header, so tokens
+// signed with a previous key keep working during rotation. With a keyring
+// configured every HMAC token must name a key in it; there is no fallback
+// to the single (or demo) secret.
+func sessionVerificationKey(token *jwt.Token) (interface{}, error) {
+	if sessionAlgIsRSA(config.SessionJWTAlg) {
+		key, err := sessionRSAKey()
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if len(config.SessionJWTKeys) > 0 {
+			return nil, fmt.Errorf("session token has no key id")
+		}
+		return sessionSecret(), nil
+	}
+
+	secret, ok := config.SessionJWTKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown session key id: %s", kid)
+	}
+	return []byte(secret), nil
+}
+
+// HMAC secret for session JWTs
+func sessionSecret() []byte {
+	if config.SessionJWTSecret != "" {
+		return []byte(config.SessionJWTSecret)
+	}
+	return []byte(demoJWTSecret)
+}
+
+// Whether alg is one of the RSA session algorithms (RS256, RS384, RS512)
+func sessionAlgIsRSA(alg string) bool {
+	return strings.HasPrefix(alg, "RS")
+}
+
+// Parse SESSION_JWT_PRIVATE_KEY, a PEM-encoded RSA private key. Escaped
+// newlines ("\n") are accepted so the key fits on one env var line.
+func parseSessionPrivateKey(raw string) (*rsa.PrivateKey, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("a PEM private key is required for RS* algorithms")
+	}
+	return jwt.ParseRSAPrivateKeyFromPEM([]byte(strings.ReplaceAll(raw, `\n`, "\n")))
+}
+
+// Parsed SESSION_JWT_PRIVATE_KEY, kept until the configured key changes
+var sessionRSAKeyCache struct {
+	sync.Mutex
+	raw string
+	key *rsa.PrivateKey
+	err error
+}
+
+// RSA key for session JWTs, parsed from the configuration on first use
+func sessionRSAKey() (*rsa.PrivateKey, error) {
+	cache := &sessionRSAKeyCache
+	cache.Lock()
+	defer cache.Unlock()
+
+	raw := config.SessionJWTPrivateKey
+	if cache.raw != raw || (cache.key == nil && cache.err == nil) {
+		cache.raw = raw
+		cache.key, cache.err = parseSessionPrivateKey(raw)
+	}
+	return cache.key, cache.err
+}
+
+// Resolve the configured session signing method (HS256 when unset)
+func sessionSigningMethod() (jwt.SigningMethod, error) {
+	return sessionSigningMethodFor(config)
+}
+
+// Resolve the session signing method named in cfg
+func sessionSigningMethodFor(cfg Config) (jwt.SigningMethod, error) {
+	switch cfg.SessionJWTAlg {
+	case "", "HS256":
+		return jwt.SigningMethodHS256, nil
+	case "HS384":
+		return jwt.SigningMethodHS384, nil
+	case "HS512":
+		return jwt.SigningMethodHS512, nil
+	case "RS256":
+		return jwt.SigningMethodRS256, nil
+	case "RS384":
+		return jwt.SigningMethodRS384, nil
+	case "RS512":
+		return jwt.SigningMethodRS512, nil
+	default:
+		return nil, fmt.Errorf("unsupported session JWT algorithm: %s", cfg.SessionJWTAlg)
+	}
+}
+
+// passwordPolicy defines the complexity rules for new passwords
+type passwordPolicy struct {
+	MinLength     int
+	RequireDigit  bool
+	RequireUpper  bool
+	RequireSymbol bool
+}
+
+// Validate a password against the configured policy, returning the first
+// rule it violates
+func validatePassword(password string) error {
+	policy := config.PasswordPolicy
+
+	if len(password) < policy.MinLength {
+		return fmt.Errorf("password must be at least %d characters", policy.MinLength)
+	}
+
+	var hasDigit, hasUpper, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if policy.RequireDigit && !hasDigit {
+		return fmt.Errorf("password must contain a digit")
+	}
+	if policy.RequireUpper && !hasUpper {
+		return fmt.Errorf("password must contain an uppercase letter")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		return fmt.Errorf("password must contain a symbol")
+	}
+	return nil
+}
 
 // Simple password hashing using SHA256 (in production, use bcrypt)
 func hashPassword(password string) string {
@@ -86,48 +375,86 @@ func verifyPassword(password, hash string) bool {
 
 // Create a session JWT for the demo
 func createSessionJWT(user DemoUser) (string, error) {
+	now := time.Now()
+	expiresAt := now.Add(24 * time.Hour)
+	jti := newRandomID()
+
 	claims := jwt.MapClaims{
+		"jti":             jti,
 		"userId":          user.ID,
 		"email":           user.Email,
 		"isAutojoinAdmin": user.IsAutojoinAdmin,
 		"role":            user.Role,
 		"groups":          user.Groups,
-		"exp":             time.Now().Add(24 * time.Hour).Unix(),
-		"iat":             time.Now().Unix(),
+		"exp":             expiresAt.Unix(),
+		"iat":             now.Unix(),
+	}
+	if user.ImpersonatedBy != "" {
+		claims["impersonatedBy"] = user.ImpersonatedBy
+	}
+	if config.SessionJWTIssuer != "" {
+		claims["iss"] = config.SessionJWTIssuer
+	}
+	if config.SessionJWTAudience != "" {
+		claims["aud"] = config.SessionJWTAudience
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtSecret))
+	method, err := sessionSigningMethod()
+	if err != nil {
+		return "", err
+	}
+
+	kid, key, err := sessionSigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Track the session so it can be revoked before it expires
+	sessions.track(sessionRecord{JTI: jti, UserID: user.ID, IssuedAt: now, ExpiresAt: expiresAt})
+	return signed, nil
 }
 
 // Verify session JWT
 func verifySessionJWT(tokenString string) (*DemoUser, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwtSecret), nil
-	})
+	method, err := sessionSigningMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pin to exactly the configured algorithm to prevent downgrades, and
+	// require the configured issuer/audience when set. Numbers decode as
+	// json.Number so numeric IDs keep full precision.
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{method.Alg()}), jwt.WithJSONNumber()}
+	if config.SessionJWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(config.SessionJWTIssuer))
+	}
+	if config.SessionJWTAudience != "" {
+		options = append(options, jwt.WithAudience(config.SessionJWTAudience))
+	}
+
+	token, err := jwt.NewParser(options...).Parse(tokenString, sessionVerificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// Convert groups back to UserGroup slice
-		var groups []UserGroup
-		if groupsInterface, exists := claims["groups"]; exists {
-			if groupsSlice, ok := groupsInterface.([]interface{}); ok {
-				for _, g := range groupsSlice {
-					if groupMap, ok := g.(map[string]interface{}); ok {
-						groups = append(groups, UserGroup{
-							Type: groupMap["type"].(string),
-							ID:   groupMap["id"].(string),
-							Name: groupMap["name"].(string),
-						})
-					}
-				}
-			}
+		jti, _ := claims["jti"].(string)
+		if jti != "" && sessions.isRevoked(jti) {
+			return nil, fmt.Errorf("session has been revoked")
+		}
+
+		groups, err := groupsClaim(claims, config.StrictGroupClaims)
+		if err != nil {
+			return nil, err
 		}
 
 		// Get isAutojoinAdmin with default false
@@ -138,38 +465,176 @@ func verifySessionJWT(tokenString string) (*DemoUser, error) {
 			}
 		}
 
+		impersonatedBy, err := stringClaim(claims, "impersonatedBy", false)
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := idClaim(claims, "userId")
+		if err != nil {
+			return nil, err
+		}
+		email, err := stringClaim(claims, "email", true)
+		if err != nil {
+			return nil, err
+		}
+		role, err := stringClaim(claims, "role", false)
+		if err != nil {
+			return nil, err
+		}
+
 		return &DemoUser{
-			ID:              claims["userId"].(string),
-			Email:           claims["email"].(string),
+			ID:              userID,
+			Email:           email,
 			IsAutojoinAdmin: isAutojoinAdmin,
-			Role:            claims["role"].(string),
+			Role:            role,
 			Groups:          groups,
+			SessionID:       jti,
+			ImpersonatedBy:  impersonatedBy,
 		}, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
+// Read an ID claim as a string. Numeric IDs (decoded as json.Number) are
+// accepted without losing precision; anything else is an error.
+func idClaim(claims jwt.MapClaims, name string) (string, error) {
+	switch value := claims[name].(type) {
+	case string:
+		if value != "" {
+			return value, nil
+		}
+	case json.Number:
+		return value.String(), nil
+	}
+	return "", fmt.Errorf("invalid token: missing/invalid %s", name)
+}
+
+// Convert the groups claim back to UserGroups. Malformed entries are
+// skipped (logged at debug level), or fail the token in strict mode.
+func groupsClaim(claims jwt.MapClaims, strict bool) ([]UserGroup, error) {
+	raw, exists := claims["groups"]
+	if !exists || raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid token: missing/invalid groups")
+	}
+
+	var groups []UserGroup
+	for i, entry := range entries {
+		groupMap, _ := entry.(map[string]interface{})
+		groupType, typeOK := groupMap["type"].(string)
+		groupID, idOK := groupMap["id"].(string)
+		groupName, nameOK := groupMap["name"].(string)
+		if !typeOK || !idOK || !nameOK {
+			if strict {
+				return nil, fmt.Errorf("invalid token: missing/invalid groups[%d]", i)
+			}
+			logDebugf("Skipping malformed group claim at index %d: %v", i, entry)
+			continue
+		}
+		groups = append(groups, UserGroup{Type: groupType, ID: groupID, Name: groupName})
+	}
+	return groups, nil
+}
+
+// Read a string claim. A missing optional claim is ""; a required one that
+// is missing or empty, or any claim of another type, is an error.
+func stringClaim(claims jwt.MapClaims, name string, required bool) (string, error) {
+	raw, exists := claims[name]
+	if !exists && !required {
+		return "", nil
+	}
+
+	value, ok := raw.(string)
+	if !ok || (required && value == "") {
+		return "", fmt.Errorf("invalid token: missing/invalid %s", name)
+	}
+	return value, nil
+}
+
+// Canonical form of an email for comparisons: trimmed and lowercased
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // Authenticate user by email and password
 func authenticateUser(email, password string) *DemoUser {
+	email = normalizeEmail(email)
+	demoUsersMu.RLock()
+	defer demoUsersMu.RUnlock()
 	for _, user := range demoUsers {
-		if user.Email == email && verifyPassword(password, user.Password) {
-			return &DemoUser{
-				ID:              user.ID,
-				Email:           user.Email,
-				IsAutojoinAdmin: user.IsAutojoinAdmin,
-				Role:            user.Role,
-				Groups:          user.Groups,
-			}
+		if user.DeletedAt != nil {
+			continue
+		}
+		if normalizeEmail(user.Email) == email && verifyPassword(password, user.Password) {
+			found := user
+			found.Password = ""
+			return &found
 		}
 	}
 	return nil
 }
 
-// Get current user from request (checks cookies for session JWT)
+// Name of the session cookie ("session" unless configured)
+func sessionCookieName() string {
+	if config.SessionCookieName == "" {
+		return "session"
+	}
+	return config.SessionCookieName
+}
+
+// Whether sessions travel in the cookie and/or the Authorization header
+func authUsesCookie() bool { return config.AuthMode != "header" }
+func authUsesHeader() bool { return config.AuthMode == "header" || config.AuthMode == "both" }
+
+// Session token to include in a response body; empty unless header auth
+// is enabled
+func sessionTokenForBody(token string) string {
+	if !authUsesHeader() {
+		return ""
+	}
+	return token
+}
+
+// Set the session cookie (no-op in header-only auth mode)
+func setSessionCookie(c *gin.Context, token string) {
+	if !authUsesCookie() {
+		return
+	}
+	c.SetCookie(sessionCookieName(), token, 24*60*60, "/", "", false, true)
+}
+
+// Clear the session cookie (no-op in header-only auth mode)
+func clearSessionCookie(c *gin.Context) {
+	if !authUsesCookie() {
+		return
+	}
+	c.SetCookie(sessionCookieName(), "", -1, "/", "", false, true)
+}
+
+// Session JWT from the Authorization header or cookie, per AUTH_MODE
+func sessionTokenFromRequest(c *gin.Context) string {
+	if authUsesHeader() {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+			return strings.TrimSpace(token)
+		}
+	}
+	if authUsesCookie() {
+		if token, err := c.Cookie(sessionCookieName()); err == nil {
+			return token
+		}
+	}
+	return ""
+}
+
+// Get current user from request (checks the session JWT)
 func getCurrentUser(c *gin.Context) *DemoUser {
-	token, err := c.Cookie("session")
-	if err != nil {
+	token := sessionTokenFromRequest(c)
+	if token == "" {
 		return nil
 	}
 
@@ -193,21 +658,78 @@ func requireAuth() gin.HandlerFunc {
 
 		// Attach user to context for use in other handlers
 		c.Set("user", user)
+		if !enforceUserQuota(c, user.ID) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// Middleware to require an autojoin admin; must run after requireAuth
+func requireAutojoinAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := c.MustGet("user").(*DemoUser)
+		if !ok || !user.IsAutojoinAdmin {
+			respondError(c, 403, "forbidden", "Admin access required")
+			return
+		}
 		c.Next()
 	}
 }
 
+// Find a demo user by ID - without password
+func findDemoUserByID(id string) *DemoUser {
+	demoUsersMu.RLock()
+	defer demoUsersMu.RUnlock()
+	for _, user := range demoUsers {
+		if user.ID == id && user.DeletedAt == nil {
+			found := user
+			found.Password = ""
+			return &found
+		}
+	}
+	return nil
+}
+
 // Get demo users (for testing) - without passwords
-func getDemoUsers() []DemoUser {
-	var users []DemoUser
+func getDemoUsers() []PublicUser {
+	demoUsersMu.RLock()
+	defer demoUsersMu.RUnlock()
+	users := make([]PublicUser, 0, len(demoUsers))
 	for _, user := range demoUsers {
-		users = append(users, DemoUser{
-			ID:              user.ID,
-			Email:           user.Email,
-			IsAutojoinAdmin: user.IsAutojoinAdmin,
-			Role:            user.Role,
-			Groups:          user.Groups,
-		})
+		if user.DeletedAt == nil {
+			users = append(users, toPublicUser(user))
+		}
 	}
 	return users
-}
\ No newline at end of file
+}
+
+// Soft-delete a demo user. Returns false if no active user has the ID.
+func softDeleteDemoUser(id string) bool {
+	demoUsersMu.Lock()
+	defer demoUsersMu.Unlock()
+	for i := range demoUsers {
+		if demoUsers[i].ID == id && demoUsers[i].DeletedAt == nil {
+			now := time.Now()
+			demoUsers[i].DeletedAt = &now
+			return true
+		}
+	}
+	return false
+}
+
+// Build the /api/auth/me envelope with fields derived from the user
+func buildMeResponse(user *DemoUser, legacy bool) MeResponse {
+	groups := make([]GroupSummary, 0, len(user.Groups))
+	for _, g := range user.Groups {
+		groups = append(groups, GroupSummary{Type: g.Type, Name: g.Name})
+	}
+
+	return MeResponse{
+		User:           userView(*user, legacy),
+		GroupCount:     len(groups),
+		HasAdminScopes: user.IsAutojoinAdmin,
+		Groups:         groups,
+		ImpersonatedBy: user.ImpersonatedBy,
+	}
+}
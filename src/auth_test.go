@@ -0,0 +1,526 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMeIncludesGroupSummaryWithoutPassword(t *testing.T) {
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("GET", "/api/auth/me", nil), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(strings.ToLower(rec.Body.String()), "password") {
+		t.Fatalf("response leaks a password field: %s", rec.Body.String())
+	}
+
+	var body MeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.GroupCount != 2 || len(body.Groups) != 2 {
+		t.Fatalf("groupCount = %d, groups = %v, want 2", body.GroupCount, body.Groups)
+	}
+	if !body.HasAdminScopes {
+		t.Fatal("hasAdminScopes = false for an autojoin admin")
+	}
+	if body.Groups[0] != (GroupSummary{Type: "team", Name: "Engineering"}) {
+		t.Fatalf("groups[0] = %+v", body.Groups[0])
+	}
+}
+
+func TestSessionJWTRoundTripsForEachHMACAlgorithm(t *testing.T) {
+	for _, alg := range []string{"HS256", "HS384", "HS512"} {
+		useConfig(t, func(cfg *Config) { cfg.SessionJWTAlg = alg })
+
+		token, err := createSessionJWT(demoUsers[1])
+		if err != nil {
+			t.Fatalf("%s: createSessionJWT: %v", alg, err)
+		}
+		user, err := verifySessionJWT(token)
+		if err != nil || user.ID != demoUsers[1].ID {
+			t.Fatalf("%s: verify = %v, %v", alg, user, err)
+		}
+	}
+}
+
+func TestSessionJWTRejectsOtherAlgorithm(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.SessionJWTAlg = "HS256" })
+	token, err := createSessionJWT(demoUsers[1])
+	if err != nil {
+		t.Fatalf("createSessionJWT: %v", err)
+	}
+
+	config.SessionJWTAlg = "HS512"
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Fatal("HS256 token accepted while HS512 is configured")
+	}
+}
+
+// PEM-encode a fresh RSA key the way SESSION_JWT_PRIVATE_KEY carries it,
+// with escaped newlines
+func newSessionRSAKey(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return strings.ReplaceAll(string(encoded), "\n", `\n`)
+}
+
+func TestSessionJWTRoundTripsWithRS256(t *testing.T) {
+	useConfig(t, func(cfg *Config) {
+		cfg.SessionJWTAlg = "RS256"
+		cfg.SessionJWTPrivateKey = newSessionRSAKey(t)
+	})
+	token, err := createSessionJWT(demoUsers[1])
+	if err != nil {
+		t.Fatalf("createSessionJWT: %v", err)
+	}
+	if user, err := verifySessionJWT(token); err != nil || user.ID != demoUsers[1].ID {
+		t.Fatalf("verify = %v, %v", user, err)
+	}
+
+	config.SessionJWTPrivateKey = newSessionRSAKey(t)
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Error("token signed with a different RSA key accepted")
+	}
+
+	config.SessionJWTAlg = "HS256"
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Error("RS256 token accepted while HS256 is configured")
+	}
+}
+
+// Replace the demo users for the duration of the test
+func useDemoUsers(t *testing.T, users []DemoUser) {
+	t.Helper()
+	saved := demoUsers
+	t.Cleanup(func() { demoUsers = saved })
+	demoUsers = users
+}
+
+func TestDemoUsersFileAuthenticates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	seed := `[{"email":"seed@example.com","password":"seedpass","role":"admin","groups":[{"type":"team","id":"t-9","name":"Seeded"}]}]`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := loadDemoUsersFile(path)
+	if err != nil {
+		t.Fatalf("loadDemoUsersFile: %v", err)
+	}
+	useDemoUsers(t, users)
+
+	user := authenticateUser("seed@example.com", "seedpass")
+	if user == nil || user.ID != "user-1" || user.Groups[0].Name != "Seeded" {
+		t.Fatalf("authenticateUser = %+v", user)
+	}
+	if authenticateUser("seed@example.com", "wrong") != nil {
+		t.Fatal("wrong password authenticated")
+	}
+}
+
+func TestDemoUsersFileRequiresPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(path, []byte(`[{"email":"seed@example.com"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadDemoUsersFile(path); err == nil {
+		t.Fatal("user without a password was accepted")
+	}
+}
+
+func TestCustomSessionCookieNameLoginMeLogout(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.SessionCookieName = "demo_sid" })
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	login := serve(router, httptest.NewRequest("POST", "/api/auth/login",
+		strings.NewReader(`{"email":"user@example.com","password":"userpass"}`)))
+	cookies := login.Result().Cookies()
+	if login.Code != 200 || len(cookies) != 1 || cookies[0].Name != "demo_sid" {
+		t.Fatalf("login status = %d, cookies = %v, want a demo_sid cookie", login.Code, cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/api/auth/me", nil)
+	req.AddCookie(cookies[0])
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Fatalf("me with custom cookie: status = %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/auth/me", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: cookies[0].Value})
+	if rec := serve(router, req); rec.Code != 401 {
+		t.Fatalf("me with default cookie name: status = %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/auth/logout", nil)
+	req.AddCookie(cookies[0])
+	cleared := serve(router, req).Result().Cookies()
+	if len(cleared) != 1 || cleared[0].Name != "demo_sid" || cleared[0].MaxAge >= 0 {
+		t.Fatalf("logout cookies = %v, want demo_sid cleared", cleared)
+	}
+}
+
+func TestMeOmitsLegacyFieldsWhenClientOptsOut(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	tests := []struct {
+		name, query, acceptVersion string
+		legacy                     bool
+	}{
+		{"default", "", "", true},
+		{"query opt-out", "?legacy=false", "", false},
+		{"accept-version 2", "", "2", false},
+		{"accept-version v1", "", "v1", true},
+	}
+	for _, tt := range tests {
+		req := withSession(t, httptest.NewRequest("GET", "/api/auth/me"+tt.query, nil), demoUsers[0])
+		if tt.acceptVersion != "" {
+			req.Header.Set("Accept-Version", tt.acceptVersion)
+		}
+		var body struct {
+			User map[string]interface{} `json:"user"`
+		}
+		if err := json.Unmarshal(serve(router, req).Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode: %v", tt.name, err)
+		}
+		_, hasRole := body.User["role"]
+		_, hasGroups := body.User["groups"]
+		if hasRole != tt.legacy || hasGroups != tt.legacy || body.User["email"] != "admin@example.com" {
+			t.Errorf("%s: user = %v, want legacy fields %v", tt.name, body.User, tt.legacy)
+		}
+	}
+}
+
+func TestValidatePasswordRules(t *testing.T) {
+	useConfig(t, func(cfg *Config) {
+		cfg.PasswordPolicy = passwordPolicy{MinLength: 8, RequireDigit: true, RequireUpper: true, RequireSymbol: true}
+	})
+
+	tests := []struct {
+		password, wantErr string
+	}{
+		{"Sh0rt!", "at least 8"},
+		{"NoDigits!!", "digit"},
+		{"nouppercase1!", "uppercase"},
+		{"NoSymbol123", "symbol"},
+		{"Val1d-Passw0rd", ""},
+	}
+	for _, tt := range tests {
+		err := validatePassword(tt.password)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", tt.password, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: err = %v, want mention of %q", tt.password, err, tt.wantErr)
+		}
+	}
+}
+
+// The session cookie set by a response
+func responseSessionCookie(t *testing.T, rec *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == sessionCookieName() {
+			return cookie
+		}
+	}
+	t.Fatalf("no session cookie in response (status %d): %s", rec.Code, rec.Body.String())
+	return nil
+}
+
+func TestImpersonateAndStop(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	me := func(cookie *http.Cookie) MeResponse {
+		req := httptest.NewRequest("GET", "/api/auth/me", nil)
+		req.AddCookie(cookie)
+		var body MeResponse
+		if err := json.Unmarshal(serve(router, req).Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode me: %v", err)
+		}
+		return body
+	}
+
+	start := withSession(t, httptest.NewRequest("POST", "/api/auth/impersonate/user-2", nil), demoUsers[0])
+	impersonating := responseSessionCookie(t, serve(router, start))
+	if body := me(impersonating); body.ImpersonatedBy != "user-1" || body.User.(map[string]interface{})["id"] != "user-2" {
+		t.Fatalf("me while impersonating = %+v", body)
+	}
+
+	stop := httptest.NewRequest("POST", "/api/auth/stop-impersonating", nil)
+	stop.AddCookie(impersonating)
+	restored := responseSessionCookie(t, serve(router, stop))
+	if body := me(restored); body.ImpersonatedBy != "" || body.User.(map[string]interface{})["id"] != "user-1" {
+		t.Fatalf("me after stopping = %+v", body)
+	}
+	if _, err := verifySessionJWT(impersonating.Value); err == nil {
+		t.Fatal("impersonation session still valid after stopping")
+	}
+}
+
+func TestImpersonateRequiresAdmin(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("POST", "/api/auth/impersonate/user-1", nil), demoUsers[1])
+	rec := serve(router, req)
+	if rec.Code != 403 || decodeErrorBody(t, rec).Code != "forbidden" {
+		t.Fatalf("status = %d, body = %s, want 403 forbidden", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSessionJWTRequiresConfiguredAudienceAndIssuer(t *testing.T) {
+	useConfig(t, func(cfg *Config) {
+		cfg.SessionJWTIssuer = "demo-go"
+		cfg.SessionJWTAudience = "demo-go"
+	})
+	token, err := createSessionJWT(demoUsers[1])
+	if err != nil {
+		t.Fatalf("createSessionJWT: %v", err)
+	}
+	if _, err := verifySessionJWT(token); err != nil {
+		t.Fatalf("matching iss/aud rejected: %v", err)
+	}
+
+	config.SessionJWTIssuer = "other-service"
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Fatal("token from another issuer accepted")
+	}
+
+	config.SessionJWTIssuer = "demo-go"
+	config.SessionJWTAudience = "other-audience"
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Fatal("token for another audience accepted")
+	}
+}
+
+// Sign a session for demoUsers[1] under the given keyring and current kid
+func signWithKeyring(t *testing.T, keys map[string]string, currentKID string) string {
+	t.Helper()
+	config.SessionJWTKeys = keys
+	config.SessionJWTCurrentKID = currentKID
+	token, err := createSessionJWT(demoUsers[1])
+	if err != nil {
+		t.Fatalf("createSessionJWT: %v", err)
+	}
+	return token
+}
+
+func TestKeyringVerifiesTokensSignedWithPreviousKey(t *testing.T) {
+	useConfig(t, nil)
+	oldToken := signWithKeyring(t, map[string]string{"old": "old-secret"}, "old")
+
+	ring := map[string]string{"old": "old-secret", "new": "new-secret"}
+	newToken := signWithKeyring(t, ring, "new")
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := verifySessionJWT(token); err != nil {
+			t.Errorf("token signed with the %s key rejected: %v", name, err)
+		}
+	}
+}
+
+func TestKeyringRejectsMissingOrUnknownKid(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.SessionJWTSecret = "" })
+	kidless := signWithKeyring(t, nil, "")
+	retired := signWithKeyring(t, map[string]string{"retired": "retired-secret"}, "retired")
+
+	signWithKeyring(t, map[string]string{"new": "new-secret"}, "new")
+	for name, token := range map[string]string{"no kid": kidless, "unknown kid": retired} {
+		if _, err := verifySessionJWT(token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func TestLoginEmailIsCaseAndWhitespaceInsensitive(t *testing.T) {
+	if user := authenticateUser("  ADMIN@Example.com ", "password123"); user == nil || user.ID != demoUsers[0].ID {
+		t.Errorf("authenticateUser = %+v, want %s", user, demoUsers[0].ID)
+	}
+}
+
+func TestHeaderAuthModeUsesBearerTokens(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.AuthMode = "header" })
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	payload := `{"email":"user@example.com","password":"userpass"}`
+	rec := serve(router, httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(payload)))
+	var body LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Token == "" {
+		t.Fatalf("login: status = %d, body = %s, want a token", rec.Code, rec.Body)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("header mode set cookies: %v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/api/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+body.Token)
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Errorf("me with bearer token: status = %d, want 200", rec.Code)
+	}
+
+	req = withSession(t, httptest.NewRequest("GET", "/api/auth/me", nil), demoUsers[1])
+	if rec := serve(router, req); rec.Code == 200 {
+		t.Error("session cookie accepted in header mode")
+	}
+}
+
+func TestUserResponsesExposeOnlyPublicFields(t *testing.T) {
+	router := gin.New()
+	setupDemoRoutes(router)
+	want := []string{"email", "groups", "id", "isAutojoinAdmin", "role"}
+
+	requests := map[string]*http.Request{
+		"users":     httptest.NewRequest("GET", "/api/demo/users", nil),
+		"protected": withSession(t, httptest.NewRequest("GET", "/api/demo/protected", nil), demoUsers[0]),
+	}
+	for name, req := range requests {
+		rec := serve(router, req)
+		var body struct {
+			User  map[string]any   `json:"user"`
+			Users []map[string]any `json:"users"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode %s: %v", name, rec.Body, err)
+		}
+		users := body.Users
+		if body.User != nil {
+			users = append(users, body.User)
+		}
+		if len(users) == 0 {
+			t.Fatalf("%s: no users in %s", name, rec.Body)
+		}
+		for _, user := range users {
+			keys := slices.Sorted(maps.Keys(user))
+			if !slices.Equal(keys, want) {
+				t.Errorf("%s: user fields = %v, want %v", name, keys, want)
+			}
+		}
+	}
+}
+
+// Sign arbitrary session claims with the configured method, key, issuer
+// and audience
+func signSessionClaims(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	if config.SessionJWTIssuer != "" {
+		claims["iss"] = config.SessionJWTIssuer
+	}
+	if config.SessionJWTAudience != "" {
+		claims["aud"] = config.SessionJWTAudience
+	}
+	method, err := sessionSigningMethod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, err := sessionSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestSessionUserIDClaim(t *testing.T) {
+	useConfig(t, nil)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	missing := signSessionClaims(t, jwt.MapClaims{"email": "a@example.com", "role": "user", "exp": exp})
+	if _, err := verifySessionJWT(missing); err == nil {
+		t.Error("token without userId verified")
+	}
+
+	numeric := signSessionClaims(t, jwt.MapClaims{"userId": 9007199254740993, "email": "a@example.com", "role": "user", "exp": exp})
+	user, err := verifySessionJWT(numeric)
+	if err != nil {
+		t.Fatalf("numeric userId rejected: %v", err)
+	}
+	if user.ID != "9007199254740993" {
+		t.Errorf("ID = %q, want full-precision 9007199254740993", user.ID)
+	}
+}
+
+func TestMalformedSessionClaimsAreErrors(t *testing.T) {
+	useConfig(t, nil)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := map[string]jwt.MapClaims{
+		"missing email":   {"userId": "user-1", "role": "user", "exp": exp},
+		"non-string role": {"userId": "user-1", "email": "a@example.com", "role": 7, "exp": exp},
+		"groups not list": {"userId": "user-1", "email": "a@example.com", "groups": "team-1", "exp": exp},
+	}
+	for name, claims := range tests {
+		token := signSessionClaims(t, claims)
+		if user, err := verifySessionJWT(token); err == nil {
+			t.Errorf("%s: verified as %+v, want error", name, user)
+		}
+	}
+
+	token := signSessionClaims(t, jwt.MapClaims{"userId": "user-1", "email": "a@example.com", "exp": exp})
+	if user, err := verifySessionJWT(token); err != nil || user.Role != "" {
+		t.Errorf("missing optional role: user = %+v, err = %v", user, err)
+	}
+}
+
+func TestMalformedGroupClaimsSkippedUnlessStrict(t *testing.T) {
+	useConfig(t, nil)
+	claims := jwt.MapClaims{
+		"userId": "user-1",
+		"email":  "a@example.com",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []any{
+			map[string]any{"type": "team"},
+			"team-2",
+			map[string]any{"type": "team", "id": "team-3", "name": "Team 3"},
+		},
+	}
+	token := signSessionClaims(t, claims)
+
+	user, err := verifySessionJWT(token)
+	if err != nil {
+		t.Fatalf("lenient mode rejected token: %v", err)
+	}
+	if len(user.Groups) != 1 || user.Groups[0].ID != "team-3" {
+		t.Errorf("groups = %+v, want only team-3", user.Groups)
+	}
+
+	config.StrictGroupClaims = true
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Error("strict mode accepted malformed groups")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
+)
+
+// Outcomes of a single batch item
+const (
+	batchStatusOK       = "ok"
+	batchStatusFailed   = "failed"
+	batchStatusSkipped  = "skipped"
+	batchStatusNotFound = "not_found"
+)
+
+// BatchItemResult is the outcome of one item in a batch request
+type BatchItemResult struct {
+	Status string      `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+func batchOK(data interface{}) BatchItemResult {
+	return BatchItemResult{Status: batchStatusOK, Data: data}
+}
+
+func batchFailed(err string) BatchItemResult {
+	return BatchItemResult{Status: batchStatusFailed, Error: err}
+}
+
+// Call work for each index in [0, n) with at most BATCH_CONCURRENCY calls
+// running at once. Workers write outcomes by index, so results line up with
+// the inputs regardless of completion order.
+func runBatch(n int, work func(i int)) {
+	var g errgroup.Group
+	g.SetLimit(config.BatchConcurrency)
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			work(i)
+			return nil
+		})
+	}
+	g.Wait()
+}
+
+// Respond with per-item results and a summary. The status is 200 when no
+// item failed, 502 when every item failed and 207 Multi-Status when mixed.
+// Skipped and not-found items count as neither, so 502 only ever means the
+// upstream calls failed.
+func respondBatch(c *gin.Context, results map[string]BatchItemResult) {
+	succeeded, failed, skipped, notFound := 0, 0, 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case batchStatusOK:
+			succeeded++
+		case batchStatusFailed:
+			failed++
+		case batchStatusNotFound:
+			notFound++
+		default:
+			skipped++
+		}
+	}
+
+	status := 200
+	if failed > 0 {
+		status = 207
+		if failed == len(results) {
+			status = 502
+		}
+	}
+
+	c.JSON(status, gin.H{
+		"results": results,
+		"summary": gin.H{
+			"total":     len(results),
+			"succeeded": succeeded,
+			"failed":    failed,
+			"skipped":   skipped,
+			"notFound":  notFound,
+		},
+	})
+}
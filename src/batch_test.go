@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+// Fake whose GetInvitation knows only the given IDs
+func knownInvitations(ids ...string) *fakeInvitations {
+	return &fakeInvitations{
+		get: func(id string) (*vortex.InvitationResult, error) {
+			for _, known := range ids {
+				if id == known {
+					return &vortex.InvitationResult{ID: id, Status: "pending"}, nil
+				}
+			}
+			return nil, &vortex.APIError{StatusCode: 404, Message: "invitation not found"}
+		},
+	}
+}
+
+func TestBatchGetSeparatesFoundAndMissing(t *testing.T) {
+	useConfig(t, nil)
+	useInvitations(t, knownInvitations("inv-1"))
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/batch-get",
+		strings.NewReader(`{"ids":["inv-1","inv-2","inv-1"]}`)), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Results map[string]struct {
+			Status string                   `json:"status"`
+			Data   *vortex.InvitationResult `json:"data"`
+		} `json:"results"`
+		Summary map[string]int `json:"summary"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if found := body.Results["inv-1"]; found.Status != batchStatusOK || found.Data == nil || found.Data.ID != "inv-1" {
+		t.Fatalf("inv-1 = %+v", found)
+	}
+	if missing := body.Results["inv-2"]; missing.Status != batchStatusNotFound || missing.Data != nil {
+		t.Fatalf("inv-2 = %+v, want not_found without data", missing)
+	}
+	if body.Summary["total"] != 2 || body.Summary["notFound"] != 1 || body.Summary["failed"] != 0 {
+		t.Fatalf("summary = %v", body.Summary)
+	}
+}
+
+func TestBatchGetRejectsTooManyIDs(t *testing.T) {
+	useInvitations(t, knownInvitations())
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	ids, _ := json.Marshal(map[string][]string{"ids": make([]string, maxBatchGetIDs+1)})
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/batch-get", strings.NewReader(string(ids))), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 400 || decodeErrorBody(t, rec).Code != "too_many_ids" {
+		t.Fatalf("status = %d, body = %s, want 400 too_many_ids", rec.Code, rec.Body.String())
+	}
+}
+
+func batchResponse(t *testing.T, results map[string]BatchItemResult) (int, map[string]int) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/batch", func(c *gin.Context) { respondBatch(c, results) })
+
+	rec := serve(router, httptest.NewRequest("GET", "/batch", nil))
+	var body struct {
+		Summary map[string]int `json:"summary"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, body.Summary
+}
+
+func TestRespondBatchStatus(t *testing.T) {
+	notFound := BatchItemResult{Status: batchStatusNotFound}
+	skipped := BatchItemResult{Status: batchStatusSkipped}
+	tests := []struct {
+		name    string
+		results map[string]BatchItemResult
+		want    int
+	}{
+		{"all ok", map[string]BatchItemResult{"a": batchOK(nil), "b": skipped}, 200},
+		{"all not found", map[string]BatchItemResult{"a": notFound, "b": notFound}, 200},
+		{"mixed", map[string]BatchItemResult{"a": batchOK(nil), "b": batchFailed("boom")}, 207},
+		{"failed and not found", map[string]BatchItemResult{"a": notFound, "b": batchFailed("boom")}, 207},
+		{"all failed", map[string]BatchItemResult{"a": batchFailed("boom"), "b": batchFailed("boom")}, 502},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := batchResponse(t, tt.results); status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunBatchBoundsConcurrency(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.BatchConcurrency = 2 })
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	done := make([]bool, 10)
+	runBatch(len(done), func(i int) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		done[i] = true
+		mu.Unlock()
+	})
+
+	if peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("item %d never ran", i)
+		}
+	}
+}
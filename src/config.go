@@ -0,0 +1,594 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config holds server settings loaded from environment variables
+type Config struct {
+	// Port the HTTP server listens on
+	Port string
+
+	// Certificate and key for serving HTTPS directly; plain HTTP when unset
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Prefix prepended to every API route, e.g. "/svc/demo"
+	APIPrefix string
+
+	// Vortex API key; the demo key is used when unset outside release mode
+	VortexAPIKey string
+
+	// Base URL for direct Vortex API calls, e.g. a staging instance
+	VortexBaseURL string
+
+	// HMAC secret for session JWTs; required in release mode
+	SessionJWTSecret string
+
+	// Optional keyring (kid -> secret) for rotating session JWT secrets,
+	// and the kid used to sign new tokens
+	SessionJWTKeys       map[string]string
+	SessionJWTCurrentKID string
+
+	// Origins allowed to make cross-origin requests ("*" for any; none
+	// disables CORS), how long browsers may cache preflights, and response
+	// headers scripts may read
+	CORSAllowedOrigins []string
+	CORSMaxAge         int
+	CORSExposedHeaders []string
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For headers are trusted
+	TrustedProxies []string
+
+	// Host header values the server answers to; empty accepts any
+	AllowedHosts []string
+
+	// Paths only served to callers connecting from a trusted proxy
+	InternalOnlyPaths []string
+
+	// Algorithm for session JWTs: HMAC (HS256, HS384, HS512) or RSA
+	// (RS256, RS384, RS512)
+	SessionJWTAlg string
+
+	// PEM-encoded RSA private key for the RS* session algorithms
+	SessionJWTPrivateKey string
+
+	// Reject session tokens with malformed group claims instead of
+	// skipping the bad entries
+	StrictGroupClaims bool
+
+	// Issuer and audience set on, and required in, session JWTs
+	SessionJWTIssuer   string
+	SessionJWTAudience string
+
+	// Gin mode ("debug", "release" or "test") and the level of the server's
+	// own startup logging
+	GinMode  string
+	LogLevel string
+
+	// Access log format: "text" (Gin default) or "json"
+	LogFormat string
+
+	// Mask emails and credentials in access logs
+	LogMaskPII bool
+
+	// Indent JSON responses by default; ?pretty= overrides per request
+	JSONPretty bool
+
+	// Whether error responses include the underlying error ("verbose") or
+	// only a code and generic message ("terse"); terse by default in
+	// release mode
+	ErrorVerbosity string
+
+	// Fraction (0.0-1.0) of 2xx requests written to the access log
+	LogSampleRate float64
+
+	// Requests slower than this also get a warn-level log line (0 disables)
+	SlowRequestThreshold time.Duration
+
+	// Maximum accepted request body size in bytes
+	MaxBodyBytes int64
+
+	// Maximum accepted request URI length (path plus query) in bytes
+	MaxURILength int
+
+	// Deadline applied to each request's context, and overrides for the
+	// auth and Vortex route groups (0 uses RequestTimeout)
+	RequestTimeout       time.Duration
+	AuthRequestTimeout   time.Duration
+	VortexRequestTimeout time.Duration
+
+	// How long shutdown waits for in-flight requests to finish
+	ShutdownTimeout time.Duration
+
+	// HTTP transport settings for calls to the Vortex API
+	VortexHTTPTimeout     time.Duration
+	VortexMaxIdleConns    int
+	VortexIdleConnTimeout time.Duration
+
+	// How often /ready re-checks that Vortex is reachable
+	ReadinessProbeInterval time.Duration
+
+	// Start with API routes answering 503 for planned maintenance; admins
+	// can flip it at runtime
+	MaintenanceMode bool
+
+	// Maximum number of Vortex calls in flight at once, and per batch
+	// request
+	VortexMaxConcurrency int
+	BatchConcurrency     int
+
+	// Optional JSON file replacing the built-in demo users
+	DemoUsersFile string
+
+	// Directory holding the frontend (index.html and assets)
+	StaticDir string
+
+	// Name of the cookie holding the session JWT
+	SessionCookieName string
+
+	// How session tokens travel: "cookie", "header" (Authorization: Bearer)
+	// or "both"
+	AuthMode string
+
+	// Rules applied whenever a password is set
+	PasswordPolicy passwordPolicy
+
+	// Cache-Control header on invitation list responses
+	InvitationListCacheControl string
+
+	// How long fetched invitations are served from the per-ID cache (0
+	// disables it)
+	InvitationCacheTTL time.Duration
+
+	// Requests each authenticated user may make per hour (0 disables)
+	UserQuotaPerHour int
+
+	// Invitations that may be created for one target per hour (0 disables)
+	InvitesPerTargetPerHour int
+
+	// Minimum time between reinvites of the same invitation (0 disables)
+	ReinviteCooldown time.Duration
+
+	// Email templates a reinvite may select with ?template=
+	ReinviteTemplates []string
+
+	// Hosts an invitation acceptance may redirect to with ?redirect=
+	AcceptRedirectAllowlist []string
+
+	// Toggles for demo-only behavior
+	Features Features
+
+	// Optional banner text the frontend shows (e.g. "Public demo")
+	DemoBanner string
+}
+
+// Features toggles optional routes; disabled routes respond 404
+type Features struct {
+	Impersonation   bool `json:"impersonation"`
+	Echo            bool `json:"echo"`
+	Stats           bool `json:"stats"`
+	DemoReset       bool `json:"demoReset"`
+	SimulatedErrors bool `json:"simulatedErrors"`
+}
+
+var config Config
+
+// Settings are looked up by env var name in three layers: command-line
+// flags, then the environment, then CONFIG_FILE
+var (
+	settingsFromFlags = make(map[string]string)
+	settingsFromFile  = make(map[string]string)
+	settingsRead      = make(map[string]bool)
+)
+
+// Command-line flags and the setting each one overrides
+var settingFlags = []struct{ name, key, usage string }{
+	{"config", "CONFIG_FILE", "path to a JSON config file"},
+	{"port", "PORT", "port to listen on"},
+	{"api-prefix", "API_PREFIX", "prefix for all /api routes"},
+	{"static-dir", "STATIC_DIR", "directory served as the frontend"},
+	{"gin-mode", "GIN_MODE", "gin mode: debug, release or test"},
+	{"log-level", "LOG_LEVEL", "log level: debug, info, warn or error"},
+}
+
+// Parse command-line flags into the highest-precedence settings layer
+func parseSettingFlags(args []string) error {
+	fs := flag.NewFlagSet("demo-go", flag.ContinueOnError)
+	values := make(map[string]*string, len(settingFlags))
+	for _, f := range settingFlags {
+		values[f.key] = fs.String(f.name, "", f.usage+" (overrides "+f.key+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		if *value != "" {
+			settingsFromFlags[key] = *value
+		}
+	}
+	return nil
+}
+
+// Look up a setting by env var name: flags override the environment,
+// which overrides CONFIG_FILE
+func lookupSetting(key string) string {
+	settingsRead[key] = true
+	if value := settingsFromFlags[key]; value != "" {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return settingsFromFile[key]
+}
+
+// Read a JSON config file keyed by env var names, e.g. {"PORT": 8080}.
+// Numbers and booleans are accepted, arrays become comma-separated lists
+// and objects are kept as JSON.
+func loadSettingsFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	settings := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			settings[key] = v
+		case float64:
+			settings[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			settings[key] = strconv.FormatBool(v)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			settings[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			encoded, _ := json.Marshal(v)
+			settings[key] = string(encoded)
+		}
+	}
+	return settings, nil
+}
+
+// Load configuration from flags, the environment and CONFIG_FILE, applying
+// defaults
+func loadConfig() Config {
+	if path := lookupSetting("CONFIG_FILE"); path != "" {
+		settings, err := loadSettingsFile(path)
+		if err != nil {
+			log.Printf("⚠️  Ignoring CONFIG_FILE: %v", err)
+		} else {
+			settingsFromFile = settings
+		}
+	}
+
+	source, err := newSecretSource(lookupSetting("SECRET_SOURCE"))
+	if err != nil {
+		log.Fatalf("Invalid SECRET_SOURCE: %v", err)
+	}
+	secrets = source
+
+	cfg := buildConfig()
+
+	// Every known setting has been read by now, so anything else is a typo
+	for key := range settingsFromFile {
+		if !settingsRead[key] {
+			log.Printf("⚠️  Ignoring unknown key %q in CONFIG_FILE", key)
+		}
+	}
+	return cfg
+}
+
+// Build the Config from the layered settings
+func buildConfig() Config {
+	return Config{
+		Port:             getEnv("PORT", "3000"),
+		APIPrefix:        normalizePrefix(lookupSetting("API_PREFIX")),
+		TLSCertFile:      lookupSetting("TLS_CERT_FILE"),
+		TLSKeyFile:       lookupSetting("TLS_KEY_FILE"),
+		VortexAPIKey:     lookupSecret("VORTEX_API_KEY"),
+		VortexBaseURL:    strings.TrimRight(getEnv("VORTEX_BASE_URL", defaultVortexAPIBaseURL), "/"),
+		SessionJWTSecret: lookupSecret("SESSION_JWT_SECRET"),
+
+		SessionJWTKeys:       getSecretStringMap("SESSION_JWT_KEYS"),
+		SessionJWTPrivateKey: lookupSecret("SESSION_JWT_PRIVATE_KEY"),
+		SessionJWTCurrentKID: lookupSetting("SESSION_JWT_CURRENT_KID"),
+
+		CORSAllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS", nil),
+		CORSMaxAge:         getEnvInt("CORS_MAX_AGE", 600),
+		CORSExposedHeaders: getEnvList("CORS_EXPOSED_HEADERS", []string{requestIDHeader, serverTimeHeader}),
+
+		TrustedProxies:    getEnvList("TRUSTED_PROXIES", []string{"127.0.0.1", "::1"}),
+		InternalOnlyPaths: getEnvList("INTERNAL_ONLY_PATHS", nil),
+		AllowedHosts:      getEnvList("ALLOWED_HOSTS", nil),
+		GinMode:           getEnv("GIN_MODE", gin.DebugMode),
+		LogLevel:          getEnv("LOG_LEVEL", "info"),
+		LogFormat:         getEnv("LOG_FORMAT", "text"),
+		LogMaskPII:        getEnvBool("LOG_MASK_PII", true),
+		JSONPretty:        getEnvBool("JSON_PRETTY", false),
+		ErrorVerbosity:    getEnv("ERROR_VERBOSITY", defaultErrorVerbosity()),
+		MaxBodyBytes:      int64(getEnvInt("MAX_BODY_BYTES", 1<<20)),
+		MaxURILength:      getEnvInt("MAX_URI_LENGTH", 8<<10),
+		RequestTimeout:    getEnvDuration("REQUEST_TIMEOUT", 30*time.Second),
+		StaticDir:         getEnv("STATIC_DIR", "./public"),
+		DemoUsersFile:     lookupSetting("DEMO_USERS_FILE"),
+
+		SessionJWTAlg:      getEnv("SESSION_JWT_ALG", "HS256"),
+		SessionJWTIssuer:   getEnv("SESSION_JWT_ISSUER", "demo-go"),
+		SessionJWTAudience: getEnv("SESSION_JWT_AUDIENCE", "demo-go"),
+		SessionCookieName:  getEnv("SESSION_COOKIE_NAME", "session"),
+		StrictGroupClaims:  getEnvBool("SESSION_STRICT_GROUPS", false),
+		AuthMode:           getEnv("AUTH_MODE", "cookie"),
+
+		VortexHTTPTimeout:     getEnvDuration("VORTEX_HTTP_TIMEOUT", 30*time.Second),
+		VortexMaxIdleConns:    getEnvInt("VORTEX_MAX_IDLE_CONNS", 100),
+		VortexIdleConnTimeout: getEnvDuration("VORTEX_IDLE_CONN_TIMEOUT", 90*time.Second),
+		VortexMaxConcurrency:  getEnvInt("VORTEX_MAX_CONCURRENCY", 50),
+		BatchConcurrency:      getEnvInt("BATCH_CONCURRENCY", 8),
+
+		ReadinessProbeInterval: getEnvDuration("READINESS_PROBE_INTERVAL", 10*time.Second),
+		MaintenanceMode:        getEnvBool("MAINTENANCE_MODE", false),
+
+		SlowRequestThreshold: getEnvDuration("SLOW_REQUEST_THRESHOLD", time.Second),
+		LogSampleRate:        getEnvFloat("LOG_SAMPLE_RATE", 1),
+		ReinviteCooldown:     getEnvDuration("REINVITE_COOLDOWN", time.Minute),
+		ShutdownTimeout:      getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		InvitationCacheTTL:   getEnvDuration("INVITATION_CACHE_TTL", 30*time.Second),
+		AuthRequestTimeout:   getEnvDuration("TIMEOUT_AUTH", 0),
+		VortexRequestTimeout: getEnvDuration("TIMEOUT_VORTEX", 0),
+		UserQuotaPerHour:     getEnvInt("USER_QUOTA_PER_HOUR", 0),
+		ReinviteTemplates:    getEnvList("REINVITE_TEMPLATES", []string{"default", "reminder"}),
+
+		InvitationListCacheControl: getEnv("INVITATION_LIST_CACHE_CONTROL", "no-store"),
+		AcceptRedirectAllowlist:    getEnvList("ACCEPT_REDIRECT_ALLOWLIST", nil),
+		InvitesPerTargetPerHour:    getEnvInt("INVITES_PER_TARGET_PER_HOUR", 3),
+
+		PasswordPolicy: passwordPolicy{
+			MinLength:     getEnvInt("PASSWORD_MIN_LEN", 8),
+			RequireDigit:  getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireUpper:  getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+			RequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		},
+
+		Features: Features{
+			Impersonation:   getEnvBool("FEATURE_IMPERSONATION", true),
+			Echo:            getEnvBool("FEATURE_ECHO", true),
+			Stats:           getEnvBool("FEATURE_STATS", true),
+			DemoReset:       getEnvBool("ALLOW_DEMO_RESET", false),
+			SimulatedErrors: getEnvBool("ALLOW_SIMULATED_ERRORS", false),
+		},
+		DemoBanner: lookupSetting("DEMO_BANNER"),
+	}
+}
+
+// Show upstream error text while developing but not in release mode
+func defaultErrorVerbosity() string {
+	if getEnv("GIN_MODE", gin.DebugMode) == gin.ReleaseMode {
+		return "terse"
+	}
+	return "verbose"
+}
+
+// Check the configuration before serving, returning every problem found
+func validateStartup(cfg Config) []string {
+	var problems []string
+	release := cfg.GinMode == gin.ReleaseMode
+
+	if cfg.GinMode != gin.DebugMode && cfg.GinMode != gin.ReleaseMode && cfg.GinMode != gin.TestMode {
+		problems = append(problems, fmt.Sprintf("GIN_MODE must be debug, release or test, got %q", cfg.GinMode))
+	}
+	if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL: %v", err))
+	}
+	if cfg.ErrorVerbosity != "terse" && cfg.ErrorVerbosity != "verbose" {
+		problems = append(problems, fmt.Sprintf("ERROR_VERBOSITY must be terse or verbose, got %q", cfg.ErrorVerbosity))
+	}
+
+	if sessionAlgIsRSA(cfg.SessionJWTAlg) {
+		if _, err := parseSessionPrivateKey(cfg.SessionJWTPrivateKey); err != nil {
+			problems = append(problems, fmt.Sprintf("SESSION_JWT_PRIVATE_KEY: %v", err))
+		}
+	} else if len(cfg.SessionJWTKeys) > 0 || cfg.SessionJWTCurrentKID != "" {
+		if _, ok := cfg.SessionJWTKeys[cfg.SessionJWTCurrentKID]; !ok {
+			problems = append(problems, "SESSION_JWT_CURRENT_KID must name a key in SESSION_JWT_KEYS")
+		}
+	} else if release && cfg.SessionJWTSecret == "" {
+		problems = append(problems, "SESSION_JWT_SECRET must be set in release mode")
+	}
+	if release && cfg.VortexAPIKey == "" {
+		problems = append(problems, "VORTEX_API_KEY must be set in release mode")
+	}
+	if err := validateBaseURL(cfg.VortexBaseURL, release); err != nil {
+		problems = append(problems, fmt.Sprintf("VORTEX_BASE_URL: %v", err))
+	}
+	if _, err := strconv.Atoi(cfg.Port); err != nil {
+		problems = append(problems, fmt.Sprintf("PORT must be numeric, got %q", cfg.Port))
+	}
+	if cfg.LogSampleRate < 0 || cfg.LogSampleRate > 1 {
+		problems = append(problems, fmt.Sprintf("LOG_SAMPLE_RATE must be between 0 and 1, got %g", cfg.LogSampleRate))
+	}
+	if cfg.VortexMaxConcurrency < 1 {
+		problems = append(problems, fmt.Sprintf("VORTEX_MAX_CONCURRENCY must be at least 1, got %d", cfg.VortexMaxConcurrency))
+	}
+	if cfg.ReadinessProbeInterval <= 0 {
+		problems = append(problems, fmt.Sprintf("READINESS_PROBE_INTERVAL must be positive, got %s", cfg.ReadinessProbeInterval))
+	}
+	if cfg.BatchConcurrency < 1 {
+		problems = append(problems, fmt.Sprintf("BATCH_CONCURRENCY must be at least 1, got %d", cfg.BatchConcurrency))
+	}
+	if cfg.AuthMode != "cookie" && cfg.AuthMode != "header" && cfg.AuthMode != "both" {
+		problems = append(problems, fmt.Sprintf("AUTH_MODE must be cookie, header or both, got %q", cfg.AuthMode))
+	}
+	if _, err := sessionSigningMethodFor(cfg); err != nil {
+		problems = append(problems, fmt.Sprintf("SESSION_JWT_ALG: %v", err))
+	}
+	if _, err := parseNetworks(cfg.TrustedProxies); err != nil {
+		problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES: %v", err))
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for _, file := range [][2]string{{"TLS_CERT_FILE", cfg.TLSCertFile}, {"TLS_KEY_FILE", cfg.TLSKeyFile}} {
+		if file[1] == "" {
+			continue
+		}
+		if _, err := os.Stat(file[1]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %q is not readable: %v", file[0], file[1], err))
+		}
+	}
+	if info, err := os.Stat(cfg.StaticDir); err != nil || !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("STATIC_DIR %q is not a directory", cfg.StaticDir))
+	}
+
+	return problems
+}
+
+// Check that a base URL is absolute http(s), and https in release mode
+func validateBaseURL(raw string, release bool) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("%q must be an absolute http(s) URL", raw)
+	}
+	if release && parsed.Scheme != "https" {
+		return fmt.Errorf("%q must use https in release mode", raw)
+	}
+	return nil
+}
+
+// Normalize a path prefix to "/a/b" form, or "" for no prefix
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// Path of an API route including the configured prefix
+func apiPath(path string) string {
+	return config.APIPrefix + path
+}
+
+// Read an env var, falling back to a default when unset
+func getEnv(key, fallback string) string {
+	if value := lookupSetting(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Read an integer env var, warning and falling back on bad values
+func getEnvInt(key string, fallback int) int {
+	raw := lookupSetting(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("⚠️  Ignoring invalid %s=%q, using %d", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+// Read a float env var, warning and falling back on bad values
+func getEnvFloat(key string, fallback float64) float64 {
+	raw := lookupSetting(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Printf("⚠️  Ignoring invalid %s=%q, using %g", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+// Read a boolean env var, warning and falling back on bad values
+func getEnvBool(key string, fallback bool) bool {
+	raw := lookupSetting(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Printf("⚠️  Ignoring invalid %s=%q, using %t", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+// Read a duration env var (e.g. "10s"), warning and falling back on bad values
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	raw := lookupSetting(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("⚠️  Ignoring invalid %s=%q, using %s", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+// Read a JSON object secret of string values, warning on bad JSON
+func getSecretStringMap(key string) map[string]string {
+	raw := lookupSecret(key)
+	if raw == "" {
+		return nil
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		log.Printf("⚠️  Ignoring invalid %s: %v", key, err)
+		return nil
+	}
+	return values
+}
+
+// Read a comma-separated env var, ignoring empty entries
+func getEnvList(key string, fallback []string) []string {
+	raw := lookupSetting(key)
+	if raw == "" {
+		return fallback
+	}
+
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
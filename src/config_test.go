@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestForwardedForHonoredOnlyFromTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1")
+	router := gin.New()
+	if err := router.SetTrustedProxies(loadConfig().TrustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	router.GET("/ip", func(c *gin.Context) { c.String(200, c.ClientIP()) })
+
+	tests := []struct {
+		remote, want string
+	}{
+		{"10.0.0.1:4000", "203.0.113.7"},
+		{"10.0.0.2:4000", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/ip", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		if got := serve(router, req).Body.String(); got != tt.want {
+			t.Errorf("from %s: client IP = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStartupReportsEveryProblem(t *testing.T) {
+	cfg := loadConfig()
+	cfg.Port = "http"
+	cfg.SessionJWTAlg = "none"
+	cfg.TrustedProxies = []string{"not-an-ip"}
+	cfg.StaticDir = t.TempDir() + "/missing"
+
+	problems := validateStartup(cfg)
+	for _, want := range []string{"PORT", "SESSION_JWT_ALG", "TRUSTED_PROXIES", "STATIC_DIR"} {
+		found := false
+		for _, problem := range problems {
+			found = found || strings.HasPrefix(problem, want)
+		}
+		if !found {
+			t.Errorf("no %s problem in %q", want, problems)
+		}
+	}
+}
+
+func TestValidateStartupRequiresSecretsInReleaseMode(t *testing.T) {
+	cfg := loadConfig()
+	cfg.GinMode = gin.ReleaseMode
+	cfg.StaticDir = t.TempDir()
+	cfg.SessionJWTSecret = ""
+	cfg.VortexAPIKey = ""
+
+	problems := validateStartup(cfg)
+	if len(problems) != 2 {
+		t.Errorf("problems = %q, want missing SESSION_JWT_SECRET and VORTEX_API_KEY", problems)
+	}
+}
+
+func TestAPIPrefixAppliesToRoutes(t *testing.T) {
+	t.Setenv("API_PREFIX", "/svc/demo/")
+	useConfig(t, nil)
+	if config.APIPrefix != "/svc/demo" {
+		t.Fatalf("APIPrefix = %q, want /svc/demo", config.APIPrefix)
+	}
+
+	router := gin.New()
+	setupDemoRoutes(router)
+	router.NoRoute(noRouteHandler(""))
+
+	if rec := serve(router, httptest.NewRequest("GET", "/svc/demo/api/demo/users", nil)); rec.Code != 200 {
+		t.Errorf("prefixed route: status = %d, want 200", rec.Code)
+	}
+	if rec := serve(router, httptest.NewRequest("GET", "/api/demo/users", nil)); rec.Code == 200 {
+		t.Error("unprefixed route still served")
+	}
+}
+
+func TestValidateStartupChecksTLSFiles(t *testing.T) {
+	cfg := loadConfig()
+	cfg.StaticDir = t.TempDir()
+	cfg.TLSCertFile = cfg.StaticDir + "/cert.pem"
+
+	problems := validateStartup(cfg)
+	if len(problems) != 2 {
+		t.Errorf("cert without key: problems = %q, want unpaired and unreadable", problems)
+	}
+
+	cfg.TLSCertFile = ""
+	if problems := validateStartup(cfg); len(problems) != 0 {
+		t.Errorf("plain HTTP: problems = %q, want none", problems)
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		release bool
+		ok      bool
+	}{
+		{"https://staging.vortexsoftware.com", true, true},
+		{"http://localhost:9000", false, true},
+		{"http://localhost:9000", true, false},
+		{"staging.vortexsoftware.com", false, false},
+		{"ftp://staging.vortexsoftware.com", false, false},
+	}
+	for _, tt := range tests {
+		if err := validateBaseURL(tt.raw, tt.release); (err == nil) != tt.ok {
+			t.Errorf("validateBaseURL(%q, release=%v) = %v, want ok=%v", tt.raw, tt.release, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateStartupChecksModeAndLogLevel(t *testing.T) {
+	cfg := loadConfig()
+	cfg.StaticDir = t.TempDir()
+	cfg.GinMode = "production"
+	cfg.LogLevel = "loud"
+
+	problems := validateStartup(cfg)
+	if len(problems) != 2 || !strings.HasPrefix(problems[0], "GIN_MODE") || !strings.HasPrefix(problems[1], "LOG_LEVEL") {
+		t.Errorf("problems = %q, want GIN_MODE and LOG_LEVEL", problems)
+	}
+}
+
+func TestSettingsLayerFlagsOverEnvOverFile(t *testing.T) {
+	savedFlags, savedFile := settingsFromFlags, settingsFromFile
+	t.Cleanup(func() { settingsFromFlags, settingsFromFile = savedFlags, savedFile })
+	settingsFromFlags = make(map[string]string)
+
+	path := t.TempDir() + "/config.json"
+	contents := `{"PORT": 8080, "API_PREFIX": "/file", "STATIC_DIR": "/srv/file", "TRUSTED_PROXIES": ["10.0.0.1", "10.0.0.2"]}`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("API_PREFIX", "/env")
+	t.Setenv("STATIC_DIR", "/srv/env")
+	if err := parseSettingFlags([]string{"-static-dir", "/srv/flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := loadConfig()
+	if cfg.Port != "8080" || cfg.APIPrefix != "/env" || cfg.StaticDir != "/srv/flag" {
+		t.Errorf("port = %q, prefix = %q, static dir = %q, want file, env and flag values", cfg.Port, cfg.APIPrefix, cfg.StaticDir)
+	}
+	if strings.Join(cfg.TrustedProxies, ",") != "10.0.0.1,10.0.0.2" {
+		t.Errorf("trusted proxies = %v, want the file's list", cfg.TrustedProxies)
+	}
+}
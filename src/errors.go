@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrorBody is the structured error returned under the "error" key
+type ErrorBody struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	RequestID string      `json:"requestId,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+
+	// Per-field messages for validation failures
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// Accepted values when an enum-like field has an unknown value
+	Allowed []string `json:"allowed,omitempty"`
+}
+
+// Respond with the structured error envelope and abort the request
+func respondError(c *gin.Context, status int, code, message string) {
+	respondErrorWithDetails(c, status, code, message, nil)
+}
+
+// Respond with the structured error envelope for an enum-like value
+// outside allowed, listing the accepted values
+func respondErrorAllowed(c *gin.Context, status int, code, message string, allowed []string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": ErrorBody{
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(requestIDKey),
+		Allowed:   allowed,
+	}})
+}
+
+// Respond with the structured error envelope including extra details
+func respondErrorWithDetails(c *gin.Context, status int, code, message string, details interface{}) {
+	c.AbortWithStatusJSON(status, gin.H{"error": ErrorBody{
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(requestIDKey),
+		Details:   details,
+	}})
+}
+
+// Respond with the structured error envelope for a failed operation. With
+// ERROR_VERBOSITY=verbose the underlying error is included in details;
+// terse responses carry only the code and message.
+func respondErrorCause(c *gin.Context, status int, code, message string, err error) {
+	var details interface{}
+	if config.ErrorVerbosity == "verbose" && err != nil {
+		details = gin.H{"error": err.Error()}
+	}
+	respondErrorWithDetails(c, status, code, message, details)
+}
+
+// Error text safe to return to clients: the error itself when verbose, a
+// generic message when terse
+func errorText(err error) string {
+	if config.ErrorVerbosity == "verbose" {
+		return err.Error()
+	}
+	return "Request to Vortex failed"
+}
+
+// Respond to a ShouldBindJSON failure: 413 for oversized bodies, field-level
+// messages for validation failures, and a generic 400 otherwise
+func respondBindError(c *gin.Context, err error) {
+	if isBodyTooLarge(err) {
+		respondError(c, 413, "payload_too_large", "Request body too large")
+		return
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		respondError(c, 400, "invalid_body", "Invalid request body")
+		return
+	}
+
+	fields := make(map[string]string, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields[fieldPath(fe)] = validationMessage(fe)
+	}
+
+	c.AbortWithStatusJSON(400, gin.H{"error": ErrorBody{
+		Code:      "validation_failed",
+		Message:   "Request validation failed",
+		RequestID: c.GetString(requestIDKey),
+		Fields:    fields,
+	}})
+}
+
+// Path of a failing field without the top-level struct name, e.g. "target.type"
+func fieldPath(fe validator.FieldError) string {
+	parts := strings.SplitN(fe.Namespace(), ".", 2)
+	if len(parts) == 2 {
+		return parts[1]
+	}
+	return fe.Field()
+}
+
+// Human-readable message for a failed validation rule
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	default:
+		return "is invalid"
+	}
+}
+
+// Report validation errors using JSON field names rather than Go names
+func registerJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func decodeErrorBody(t *testing.T, rec *httptest.ResponseRecorder) ErrorBody {
+	t.Helper()
+	var body struct {
+		Error ErrorBody `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return body.Error
+}
+
+func TestBindErrorsReportJSONFieldNames(t *testing.T) {
+	router := gin.New()
+	setupAuthRoutes(router)
+	setupVortexRoutes(router)
+
+	rec := serve(router, httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email":"admin@example.com"}`)))
+	body := decodeErrorBody(t, rec)
+	if rec.Code != 400 || body.Code != "validation_failed" {
+		t.Fatalf("status = %d, error = %+v, want 400 validation_failed", rec.Code, body)
+	}
+	if len(body.Fields) != 1 || body.Fields["password"] != "is required" {
+		t.Fatalf("fields = %v, want only password required", body.Fields)
+	}
+
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/accept", strings.NewReader(`{"target":{"type":"email"}}`)), demoUsers[0])
+	rec = serve(router, req)
+	if fields := decodeErrorBody(t, rec).Fields; fields["invitationIds"] != "is required" {
+		t.Fatalf("fields = %v, want invitationIds required", fields)
+	}
+
+	rec = serve(router, httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`not json`)))
+	if body := decodeErrorBody(t, rec); body.Code != "invalid_body" || body.Fields != nil {
+		t.Fatalf("malformed JSON error = %+v, want invalid_body without fields", body)
+	}
+}
+
+func TestErrorVerbosityControlsUpstreamText(t *testing.T) {
+	upstream := errors.New("vortex API error 500: secret detail")
+	router := gin.New()
+	router.GET("/fail", func(c *gin.Context) {
+		respondErrorCause(c, 500, "update_failed", "Failed to update invitation", upstream)
+	})
+
+	useConfig(t, func(cfg *Config) { cfg.ErrorVerbosity = "verbose" })
+	rec := serve(router, httptest.NewRequest("GET", "/fail", nil))
+	if !strings.Contains(rec.Body.String(), "secret detail") || errorText(upstream) != upstream.Error() {
+		t.Errorf("verbose: body = %s, errorText = %q", rec.Body, errorText(upstream))
+	}
+
+	config.ErrorVerbosity = "terse"
+	rec = serve(router, httptest.NewRequest("GET", "/fail", nil))
+	if strings.Contains(rec.Body.String(), "secret detail") || decodeErrorBody(t, rec).Code != "update_failed" {
+		t.Errorf("terse: body = %s", rec.Body)
+	}
+	if strings.Contains(errorText(upstream), "secret detail") {
+		t.Errorf("terse errorText = %q", errorText(upstream))
+	}
+}
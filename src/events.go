@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+// InvitationEvent describes a change to a group's invitations
+type InvitationEvent struct {
+	Type          string   `json:"type"`
+	GroupType     string   `json:"groupType"`
+	GroupID       string   `json:"groupId"`
+	InvitationIDs []string `json:"invitationIds,omitempty"`
+	Timestamp     string   `json:"timestamp"`
+}
+
+// eventBroker fans invitation events out to in-process subscribers
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan InvitationEvent]struct{}
+}
+
+var invitationEvents = newEventBroker()
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[string]map[chan InvitationEvent]struct{})}
+}
+
+func groupEventKey(groupType, groupID string) string {
+	return groupType + "/" + groupID
+}
+
+// Subscribe to a group's events. The returned function must be called to
+// release the subscription.
+func (b *eventBroker) subscribe(groupType, groupID string) (<-chan InvitationEvent, func()) {
+	key := groupEventKey(groupType, groupID)
+	ch := make(chan InvitationEvent, 16)
+
+	b.mu.Lock()
+	if b.subscribers[key] == nil {
+		b.subscribers[key] = make(map[chan InvitationEvent]struct{})
+	}
+	b.subscribers[key][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[key], ch)
+		if len(b.subscribers[key]) == 0 {
+			delete(b.subscribers, key)
+		}
+	}
+}
+
+// Publish an event to a group's subscribers. Slow subscribers miss events
+// rather than blocking the publisher.
+func (b *eventBroker) publish(event InvitationEvent) {
+	key := groupEventKey(event.GroupType, event.GroupID)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[key] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Record a change to a group's invitations made through this server
+func publishInvitationEvent(eventType, groupType, groupID string, invitationIDs []string) {
+	invitationEvents.publish(InvitationEvent{
+		Type:          eventType,
+		GroupType:     groupType,
+		GroupID:       groupID,
+		InvitationIDs: invitationIDs,
+		Timestamp:     formatTimestamp(time.Now()),
+	})
+}
+
+// Record a change to an invitation for each group it belongs to
+func publishInvitationGroupEvents(eventType string, invitation *vortex.InvitationResult, invitationIDs []string) {
+	if invitation == nil {
+		return
+	}
+	for _, group := range invitation.Groups {
+		publishInvitationEvent(eventType, group.Type, group.GroupID, invitationIDs)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+func TestStreamDeliversEventFromMutatingHandler(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.ReinviteCooldown = 0 })
+	useInvitations(t, &fakeInvitations{
+		reinvite: func(id string) (*vortex.InvitationResult, error) {
+			return &vortex.InvitationResult{ID: id, Groups: []vortex.InvitationGroup{{Type: "team", GroupID: "team-1"}}}, nil
+		},
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	req := withSession(t, httptest.NewRequest("GET", server.URL+"/api/vortex/invitations/stream?groupType=team&groupId=team-1", nil), demoUsers[0])
+	req.RequestURI = ""
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, Content-Type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// Headers are flushed after subscribing, so the event can't be missed
+	reinvite := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/inv-1/reinvite", nil), demoUsers[0])
+	if rec := serve(router, reinvite); rec.Code != 200 {
+		t.Fatalf("reinvite status = %d", rec.Code)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var eventName, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			eventName = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+
+	var event InvitationEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("decode %q: %v", data, err)
+	}
+	if eventName != "invitation" || event.Type != "reinvited" || event.GroupID != "team-1" || len(event.InvitationIDs) != 1 {
+		t.Fatalf("event %q = %+v", eventName, event)
+	}
+}
+
+func TestUnsubscribeReleasesGroup(t *testing.T) {
+	broker := newEventBroker()
+	_, unsubscribe := broker.subscribe("team", "team-1")
+	unsubscribe()
+
+	if len(broker.subscribers) != 0 {
+		t.Fatalf("subscribers = %v, want none after unsubscribe", broker.subscribers)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HistoryEntry is one step in an invitation's lifecycle
+type HistoryEntry struct {
+	Event     string `json:"event"`
+	Timestamp string `json:"timestamp"`
+
+	// ID of the demo user who made the change; empty for events reported
+	// by Vortex itself
+	Actor string `json:"actor,omitempty"`
+}
+
+// Most entries kept per invitation; older ones are dropped first
+const maxHistoryEntries = 100
+
+// historyLog is an in-memory audit log of changes made through this server,
+// keyed by invitation ID
+type historyLog struct {
+	mu      sync.Mutex
+	entries map[string][]HistoryEntry
+}
+
+var invitationHistory = &historyLog{entries: make(map[string][]HistoryEntry)}
+
+func (h *historyLog) record(event, actor string, ids ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry := HistoryEntry{Event: event, Timestamp: formatTimestamp(time.Now()), Actor: actor}
+	for _, id := range ids {
+		entries := append(h.entries[id], entry)
+		if len(entries) > maxHistoryEntries {
+			entries = entries[len(entries)-maxHistoryEntries:]
+		}
+		h.entries[id] = entries
+	}
+}
+
+func (h *historyLog) get(id string) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]HistoryEntry(nil), h.entries[id]...)
+}
+
+func (h *historyLog) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = make(map[string][]HistoryEntry)
+}
+
+// Record a lifecycle event for invitations, attributed to the signed-in user
+func recordInvitationHistory(c *gin.Context, event string, ids ...string) {
+	var actor string
+	if user, ok := c.Get("user"); ok {
+		actor = user.(*DemoUser).ID
+	}
+	invitationHistory.record(event, actor, ids...)
+}
+
+// Build an invitation's history from the audit log, adding its creation
+// from Vortex when it wasn't created through this server
+func buildInvitationHistory(id, createdAt string) []HistoryEntry {
+	entries := invitationHistory.get(id)
+
+	created := false
+	for _, entry := range entries {
+		if entry.Event == "created" {
+			created = true
+			break
+		}
+	}
+	if !created && createdAt != "" {
+		entries = append(entries, HistoryEntry{Event: "created", Timestamp: createdAt})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return parseTimestamp(entries[i].Timestamp).Before(parseTimestamp(entries[j].Timestamp))
+	})
+	return entries
+}
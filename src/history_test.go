@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+func TestInvitationHistoryListsChangesInOrder(t *testing.T) {
+	useConfig(t, nil)
+	t.Cleanup(invitationHistory.reset)
+	useInvitations(t, &fakeInvitations{get: func(id string) (*vortex.InvitationResult, error) {
+		return &vortex.InvitationResult{ID: id, CreatedAt: "2024-01-01T00:00:00Z"}, nil
+	}})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("DELETE", "/api/vortex/invitations/hist-1", nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Fatalf("revoke: status = %d", rec.Code)
+	}
+
+	rec := serve(router, withSession(t, httptest.NewRequest("GET", "/api/vortex/invitations/hist-1/history", nil), demoUsers[0]))
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		InvitationID string         `json:"invitationId"`
+		Events       []HistoryEntry `json:"events"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+
+	if body.InvitationID != "hist-1" || len(body.Events) != 2 {
+		t.Fatalf("body = %s", rec.Body)
+	}
+	if got := body.Events[0]; got.Event != "created" || got.Timestamp != "2024-01-01T00:00:00Z" || got.Actor != "" {
+		t.Errorf("first event = %+v, want Vortex creation", got)
+	}
+	if got := body.Events[1]; got.Event != "revoked" || got.Actor != demoUsers[0].ID {
+		t.Errorf("second event = %+v, want revoked by %s", got, demoUsers[0].ID)
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+// InvitationStatus is the lifecycle status of an invitation
+type InvitationStatus string
+
+const (
+	InvitationStatusPending  InvitationStatus = "pending"
+	InvitationStatusAccepted InvitationStatus = "accepted"
+	InvitationStatusRevoked  InvitationStatus = "revoked"
+	InvitationStatusExpired  InvitationStatus = "expired"
+)
+
+// All statuses accepted by the status filters
+var invitationStatuses = []InvitationStatus{
+	InvitationStatusPending,
+	InvitationStatusAccepted,
+	InvitationStatusRevoked,
+	InvitationStatusExpired,
+}
+
+// Parse a status name (case-insensitive), rejecting unknown values
+func parseInvitationStatus(raw string) (InvitationStatus, error) {
+	candidate := InvitationStatus(strings.ToLower(strings.TrimSpace(raw)))
+	for _, status := range invitationStatuses {
+		if candidate == status {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("invalid status %q (allowed: %s)", raw, joinStatuses(invitationStatuses))
+}
+
+// Parse a comma-separated list of statuses
+func parseInvitationStatuses(raw string) ([]InvitationStatus, error) {
+	var statuses []InvitationStatus
+	for _, part := range strings.Split(raw, ",") {
+		status, err := parseInvitationStatus(part)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func joinStatuses(statuses []InvitationStatus) string {
+	return strings.Join(statusNames(statuses), ", ")
+}
+
+func statusNames(statuses []InvitationStatus) []string {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+	return names
+}
+
+// Apply the query parameters shared by the invitation list handlers,
+// returning the page and, when more follow, the cursor for the next one.
+// Responds with 400 and returns false when a parameter is malformed.
+func applyInvitationListQuery(c *gin.Context, invitations []vortex.InvitationResult) ([]vortex.InvitationResult, string, bool) {
+	if raw := c.Query("createdAfter"); raw != "" {
+		after, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, 400, "invalid_query", "createdAfter must be an RFC3339 timestamp")
+			return nil, "", false
+		}
+		invitations = filterCreatedAfter(invitations, after)
+	}
+
+	if raw := c.Query("status"); raw != "" {
+		statuses, err := parseInvitationStatuses(raw)
+		if err != nil {
+			respondErrorAllowed(c, 400, "invalid_status", err.Error(), statusNames(invitationStatuses))
+			return nil, "", false
+		}
+		invitations = filterByStatus(invitations, statuses)
+	}
+
+	if raw := c.Query("expiringWithin"); raw != "" {
+		window, err := time.ParseDuration(raw)
+		if err != nil || window <= 0 {
+			respondError(c, 400, "invalid_query", "expiringWithin must be a positive duration such as 48h")
+			return nil, "", false
+		}
+		invitations = filterExpiringWithin(invitations, window, time.Now())
+	}
+
+	sortBy := c.DefaultQuery("sortBy", "createdAt")
+	key, ok := invitationSortKeys[sortBy]
+	if !ok {
+		respondErrorAllowed(c, 400, "invalid_sort", "Unsupported sortBy field", []string{"createdAt", "email", "status"})
+		return nil, "", false
+	}
+
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		respondError(c, 400, "invalid_sort", "order must be asc or desc")
+		return nil, "", false
+	}
+	// Sort a copy; the slice may be shared with the fallback cache
+	invitations = append([]vortex.InvitationResult(nil), invitations...)
+	sortInvitations(invitations, key, order == "desc")
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeInvitationCursor(raw)
+		if err != nil || cursor.SortBy != sortBy || cursor.Order != order {
+			respondError(c, 400, "invalid_cursor", "cursor is malformed or was issued for a different sortBy/order")
+			return nil, "", false
+		}
+		invitations = invitationsAfterCursor(invitations, cursor, key, order == "desc")
+	}
+
+	// Without a limit the rest of the list is returned in one page
+	raw := c.Query("limit")
+	if raw == "" {
+		return invitations, "", true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxInvitationPageSize {
+		respondError(c, 400, "invalid_query", fmt.Sprintf("limit must be between 1 and %d", maxInvitationPageSize))
+		return nil, "", false
+	}
+	if len(invitations) <= limit {
+		return invitations, "", true
+	}
+
+	page := invitations[:limit]
+	last := page[limit-1]
+	next := invitationCursor{SortBy: sortBy, Order: order, Key: key(last), ID: last.ID}
+	return page, next.encode(), true
+}
+
+// InvitationView is an invitation as returned by the list handlers
+type InvitationView struct {
+	vortex.InvitationResult
+
+	// When the invitation expires; null when it has no known expiry
+	ExpiresAt *time.Time `json:"expiresAt"`
+
+	// Seconds until expiry (negative once expired); null when it has no
+	// known expiry
+	ExpiresInSeconds *int64 `json:"expiresInSeconds"`
+}
+
+// Build list views with computed expiry fields
+func invitationViews(invitations []vortex.InvitationResult, now time.Time) []InvitationView {
+	views := make([]InvitationView, len(invitations))
+	for i, invitation := range invitations {
+		expiresAt := invitationExpiry(invitation)
+		views[i] = InvitationView{
+			InvitationResult: invitation,
+			ExpiresAt:        expiresAt,
+			ExpiresInSeconds: secondsUntil(expiresAt, now),
+		}
+	}
+	return views
+}
+
+// When an invitation expires, or nil if unknown. The SDK's InvitationResult
+// does not expose an expiry yet, so every invitation is treated as having
+// none until it does.
+func invitationExpiry(invitation vortex.InvitationResult) *time.Time {
+	return nil
+}
+
+// Seconds from now until expiresAt; nil when there is no expiry
+func secondsUntil(expiresAt *time.Time, now time.Time) *int64 {
+	if expiresAt == nil {
+		return nil
+	}
+	seconds := int64(expiresAt.Sub(now).Seconds())
+	return &seconds
+}
+
+// Whether expiresAt is unexpired and falls within the window from now
+func expiresWithin(expiresAt *time.Time, window time.Duration, now time.Time) bool {
+	return expiresAt != nil && !expiresAt.Before(now) && expiresAt.Sub(now) <= window
+}
+
+// Keep unexpired invitations that expire within the window
+func filterExpiringWithin(invitations []vortex.InvitationResult, window time.Duration, now time.Time) []vortex.InvitationResult {
+	filtered := make([]vortex.InvitationResult, 0, len(invitations))
+	for _, invitation := range invitations {
+		if expiresWithin(invitationExpiry(invitation), window, now) {
+			filtered = append(filtered, invitation)
+		}
+	}
+	return filtered
+}
+
+// Fixed-width UTC timestamps compare correctly as strings
+const sortableTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Sort key for each supported sortBy field; keys compare as strings
+var invitationSortKeys = map[string]func(invitation vortex.InvitationResult) string{
+	"createdAt": func(invitation vortex.InvitationResult) string {
+		return parseTimestamp(invitation.CreatedAt).UTC().Format(sortableTimeFormat)
+	},
+	"email": func(invitation vortex.InvitationResult) string {
+		return strings.ToLower(invitationEmail(invitation))
+	},
+	"status": func(invitation vortex.InvitationResult) string {
+		return invitation.Status
+	},
+}
+
+// Compare two (sort key, ID) positions; the ID breaks ties so the order is
+// total and cursors resume at an exact spot
+func comparePosition(keyA, idA, keyB, idB string) int {
+	if c := strings.Compare(keyA, keyB); c != 0 {
+		return c
+	}
+	return strings.Compare(idA, idB)
+}
+
+// Sort invitations in place by key, then ID
+func sortInvitations(invitations []vortex.InvitationResult, key func(vortex.InvitationResult) string, descending bool) {
+	sort.SliceStable(invitations, func(i, j int) bool {
+		c := comparePosition(key(invitations[i]), invitations[i].ID, key(invitations[j]), invitations[j].ID)
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// Largest page a list request may ask for with ?limit=
+const maxInvitationPageSize = 100
+
+// invitationCursor marks the last invitation of a page. It is returned to
+// clients as opaque base64 and only valid for the same sortBy and order.
+type invitationCursor struct {
+	SortBy string `json:"s"`
+	Order  string `json:"o"`
+	Key    string `json:"k"`
+	ID     string `json:"id"`
+}
+
+func (ic invitationCursor) encode() string {
+	data, _ := json.Marshal(ic)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeInvitationCursor(raw string) (invitationCursor, error) {
+	var cursor invitationCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+// Drop sorted invitations up to and including the cursor position. Resuming
+// from the position rather than an offset means invitations added or
+// removed before it don't shift the next page.
+func invitationsAfterCursor(invitations []vortex.InvitationResult, cursor invitationCursor, key func(vortex.InvitationResult) string, descending bool) []vortex.InvitationResult {
+	start := sort.Search(len(invitations), func(i int) bool {
+		c := comparePosition(key(invitations[i]), invitations[i].ID, cursor.Key, cursor.ID)
+		if descending {
+			return c < 0
+		}
+		return c > 0
+	})
+	return invitations[start:]
+}
+
+// The invitation's email target, falling back to its first target value
+func invitationEmail(invitation vortex.InvitationResult) string {
+	for _, target := range invitation.Target {
+		if target.Type == "email" {
+			return target.Value
+		}
+	}
+	if len(invitation.Target) > 0 {
+		return invitation.Target[0].Value
+	}
+	return ""
+}
+
+// Parse an RFC3339 timestamp, returning the zero time when malformed
+func parseTimestamp(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Keep invitations created strictly after the cursor
+func filterCreatedAfter(invitations []vortex.InvitationResult, after time.Time) []vortex.InvitationResult {
+	filtered := make([]vortex.InvitationResult, 0, len(invitations))
+	for _, invitation := range invitations {
+		createdAt, err := time.Parse(time.RFC3339, invitation.CreatedAt)
+		if err != nil {
+			continue
+		}
+		if createdAt.After(after) {
+			filtered = append(filtered, invitation)
+		}
+	}
+	return filtered
+}
+
+// Keep invitations whose status is one of the given statuses
+func filterByStatus(invitations []vortex.InvitationResult, statuses []InvitationStatus) []vortex.InvitationResult {
+	filtered := make([]vortex.InvitationResult, 0, len(invitations))
+	for _, invitation := range invitations {
+		for _, status := range statuses {
+			if InvitationStatus(invitation.Status) == status {
+				filtered = append(filtered, invitation)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// Count invitations by status. Every known status is always present so
+// an empty group reports zeros rather than missing keys.
+func countInvitationsByStatus(invitations []vortex.InvitationResult) map[string]int {
+	counts := make(map[string]int, len(invitationStatuses))
+	for _, status := range invitationStatuses {
+		counts[string(status)] = 0
+	}
+	for _, invitation := range invitations {
+		status := invitation.Status
+		if status == "" {
+			status = "unknown"
+		}
+		counts[status]++
+	}
+	return counts
+}
+
+// cooldownTracker suppresses repeats of an action per key for a period
+type cooldownTracker struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+var reinviteCooldowns = &cooldownTracker{until: make(map[string]time.Time)}
+
+// Claim key for period. If it is still cooling down, returns false and how
+// long until it may be retried. Claiming before the action (rather than
+// after) keeps two simultaneous requests from both going through.
+func (t *cooldownTracker) claim(key string, period time.Duration) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if until, ok := t.until[key]; ok && now.Before(until) {
+		return until.Sub(now), false
+	}
+	for k, until := range t.until {
+		if now.After(until) {
+			delete(t.until, k)
+		}
+	}
+	t.until[key] = now.Add(period)
+	return 0, true
+}
+
+// Release a claim after the action failed so it can be retried at once
+func (t *cooldownTracker) release(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.until, key)
+}
+
+func (t *cooldownTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.until = make(map[string]time.Time)
+}
+
+// Validate an acceptance redirect against the allowlisted hosts and return
+// it without credentials or fragment. Only absolute http(s) URLs are
+// accepted, so protocol-relative and script URLs can't slip through.
+func sanitizeAcceptRedirect(raw string, allowedHosts []string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("redirect must be an absolute http or https URL")
+	}
+
+	host := u.Hostname()
+	for _, allowed := range allowedHosts {
+		if strings.EqualFold(host, allowed) {
+			u.User = nil
+			u.Fragment = ""
+			return u.String(), nil
+		}
+	}
+	return "", fmt.Errorf("redirect host %q is not allowed", host)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logLevel orders the server's own log lines by severity
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case levelDebug:
+		return "debug"
+	case levelWarn:
+		return "warn"
+	case levelError:
+		return "error"
+	}
+	return "info"
+}
+
+// Minimum level written by logInfof, set from LOG_LEVEL at startup
+var currentLogLevel = levelInfo
+
+// Parse a LOG_LEVEL value
+func parseLogLevel(value string) (logLevel, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return levelDebug, nil
+	case "info":
+		return levelInfo, nil
+	case "warn", "warning":
+		return levelWarn, nil
+	case "error":
+		return levelError, nil
+	}
+	return levelInfo, fmt.Errorf("unknown level %q (use debug, info, warn or error)", value)
+}
+
+// Log a debug line, only when LOG_LEVEL is debug
+func logDebugf(format string, args ...interface{}) {
+	if currentLogLevel <= levelDebug {
+		log.Printf(format, args...)
+	}
+}
+
+// Log an informational line, unless LOG_LEVEL is warn or higher
+func logInfof(format string, args ...interface{}) {
+	if currentLogLevel <= levelInfo {
+		log.Printf(format, args...)
+	}
+}
+
+// Log a lifecycle event. With LOG_FORMAT=json it is one JSON line of
+// key/value fields alongside the access log; otherwise the friendly text
+// line is written to the standard logger.
+func logEvent(level logLevel, component, event string, fields map[string]interface{}, text string) {
+	if level < currentLogLevel {
+		return
+	}
+	if config.LogFormat != "json" {
+		log.Print(text)
+		return
+	}
+
+	entry := map[string]interface{}{
+		"level":     level.String(),
+		"timestamp": time.Now().Format(time.RFC3339Nano),
+		"component": component,
+		"event":     event,
+	}
+	for key, value := range fields {
+		entry[key] = value
+	}
+	encoded, _ := json.Marshal(entry)
+	fmt.Fprintln(gin.DefaultWriter, string(encoded))
+}
+
+// Log a text-only info line, such as startup hints for humans; JSON output
+// carries the same facts as logEvent fields
+func logTextf(format string, args ...interface{}) {
+	if config.LogFormat != "json" {
+		logInfof(format, args...)
+	}
+}
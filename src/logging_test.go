@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]logLevel{
+		"debug":   levelDebug,
+		"INFO":    levelInfo,
+		"warn":    levelWarn,
+		"warning": levelWarn,
+		"error":   levelError,
+	}
+	for value, want := range tests {
+		if got, err := parseLogLevel(value); err != nil || got != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseLogLevel("verbose"); err == nil {
+		t.Error("unknown level accepted")
+	}
+}
+
+func TestLogEventWritesOneJSONLine(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.LogFormat = "json" })
+	var buf bytes.Buffer
+	saved := gin.DefaultWriter
+	t.Cleanup(func() { gin.DefaultWriter = saved })
+	gin.DefaultWriter = &buf
+
+	logEvent(levelInfo, "server", "starting", map[string]interface{}{"port": "3000"}, "🚀 starting")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("not one JSON line: %q", buf.String())
+	}
+	if entry["level"] != "info" || entry["component"] != "server" || entry["event"] != "starting" || entry["port"] != "3000" {
+		t.Errorf("entry = %v", entry)
+	}
+
+	buf.Reset()
+	logEvent(levelDebug, "server", "noisy", nil, "noisy")
+	if buf.Len() != 0 {
+		t.Errorf("debug event written at info level: %q", buf.String())
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	registerJSONFieldNames()
+	os.Exit(m.Run())
+}
+
+// Run the test against the default configuration, adjusted by mutate, and
+// restore the previous configuration afterwards
+func useConfig(t *testing.T, mutate func(*Config)) {
+	t.Helper()
+	saved := config
+	t.Cleanup(func() { config = saved })
+
+	config = buildConfig()
+	if mutate != nil {
+		mutate(&config)
+	}
+}
+
+// Serve one request through router and return the recorded response
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// Attach a freshly signed session cookie for user to req
+func withSession(t *testing.T, req *http.Request, user DemoUser) *http.Request {
+	t.Helper()
+	token, err := createSessionJWT(user)
+	if err != nil {
+		t.Fatalf("createSessionJWT: %v", err)
+	}
+	req.AddCookie(&http.Cookie{Name: sessionCookieName(), Value: token})
+	return req
+}
+
+// fakeInvitations stands in for the Vortex SDK; unset hooks return empty results
+type fakeInvitations struct {
+	byTarget    func(targetType, targetValue string) ([]vortex.InvitationResult, error)
+	get         func(id string) (*vortex.InvitationResult, error)
+	revoke      func(id string) error
+	accept      func(ids []string, target vortex.InvitationTarget) (*vortex.InvitationResult, error)
+	byGroup     func(groupType, groupID string) ([]vortex.InvitationResult, error)
+	deleteGroup func(groupType, groupID string) error
+	reinvite    func(id string) (*vortex.InvitationResult, error)
+}
+
+func (f *fakeInvitations) GetInvitationsByTarget(targetType, targetValue string) ([]vortex.InvitationResult, error) {
+	if f.byTarget == nil {
+		return nil, nil
+	}
+	return f.byTarget(targetType, targetValue)
+}
+
+func (f *fakeInvitations) GetInvitation(id string) (*vortex.InvitationResult, error) {
+	if f.get == nil {
+		return &vortex.InvitationResult{ID: id}, nil
+	}
+	return f.get(id)
+}
+
+func (f *fakeInvitations) RevokeInvitation(id string) error {
+	if f.revoke == nil {
+		return nil
+	}
+	return f.revoke(id)
+}
+
+func (f *fakeInvitations) AcceptInvitations(ids []string, target vortex.InvitationTarget) (*vortex.InvitationResult, error) {
+	if f.accept == nil {
+		return &vortex.InvitationResult{}, nil
+	}
+	return f.accept(ids, target)
+}
+
+func (f *fakeInvitations) GetInvitationsByGroup(groupType, groupID string) ([]vortex.InvitationResult, error) {
+	if f.byGroup == nil {
+		return nil, nil
+	}
+	return f.byGroup(groupType, groupID)
+}
+
+func (f *fakeInvitations) DeleteInvitationsByGroup(groupType, groupID string) error {
+	if f.deleteGroup == nil {
+		return nil
+	}
+	return f.deleteGroup(groupType, groupID)
+}
+
+func (f *fakeInvitations) Reinvite(id string) (*vortex.InvitationResult, error) {
+	if f.reinvite == nil {
+		return &vortex.InvitationResult{ID: id}, nil
+	}
+	return f.reinvite(id)
+}
+
+// Route invitation calls to fake for the duration of the test
+func useInvitations(t *testing.T, fake *fakeInvitations) {
+	t.Helper()
+	saved := vortexInvitations
+	t.Cleanup(func() { vortexInvitations = saved })
+	vortexInvitations = fake
+	useVortexSlots(t, 50)
+}
+
+// Bound concurrent Vortex calls to slots for the duration of the test
+func useVortexSlots(t *testing.T, slots int) {
+	t.Helper()
+	saved := vortexSlots
+	t.Cleanup(func() { vortexSlots = saved })
+	vortexSlots = make(chan struct{}, slots)
+}
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "requestID"
+)
+
+// Assign each request an ID, reusing a reasonable one supplied by the client
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRandomID()
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Header(requestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// Allow cross-origin requests from configured origins. Preflights are
+// answered here with a cacheable 204. Listed origins get credentials so the
+// session cookie works cross-origin; "*" admits any other origin with a
+// literal "*" and no credentials, so arbitrary sites can't ride the session.
+func corsMiddleware(origins []string, maxAge int, exposed []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !(allowed["*"] || allowed[origin]) {
+			c.Next()
+			return
+		}
+
+		if allowed[origin] {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Writer.Header().Add("Vary", "Origin")
+		} else {
+			c.Header("Access-Control-Allow-Origin", "*")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			if headers := c.GetHeader("Access-Control-Request-Headers"); headers != "" {
+				c.Header("Access-Control-Allow-Headers", headers)
+			}
+			if maxAge > 0 {
+				c.Header("Access-Control-Max-Age", strconv.Itoa(maxAge))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if len(exposed) > 0 {
+			c.Header("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+		}
+		c.Next()
+	}
+}
+
+// Generate a random 128-bit hex identifier
+func newRandomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Recover from handler panics and respond with the structured error envelope.
+// The stack trace is only included in the response in debug mode.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				stack := debug.Stack()
+				log.Printf("panic recovered (request %s, client %s): %v\n%s", c.GetString(requestIDKey), c.ClientIP(), rec, stack)
+
+				var details interface{}
+				if gin.IsDebugging() {
+					details = gin.H{"panic": fmt.Sprint(rec), "stack": string(stack)}
+				}
+				respondErrorWithDetails(c, 500, "internal_error", "Internal server error", details)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// Access log middleware in the configured format
+func accessLogger() gin.HandlerFunc {
+	logConfig := gin.LoggerConfig{Output: gin.DefaultWriter}
+	if config.LogFormat == "json" {
+		logConfig.Formatter = jsonLogFormatter
+	}
+	if config.LogMaskPII {
+		logConfig.Output = piiMaskingWriter{w: logConfig.Output}
+	}
+	if config.LogSampleRate < 1 {
+		logConfig.Skip = func(c *gin.Context) bool {
+			status := c.Writer.Status()
+			return status >= 200 && status < 300 && !sampled(c.GetString(requestIDKey), config.LogSampleRate)
+		}
+	}
+	return gin.LoggerWithConfig(logConfig)
+}
+
+// Decide deterministically whether a request ID falls within the sample
+// rate, so every log line for one request makes the same choice
+func sampled(requestID string, rate float64) bool {
+	hash := fnv.New64a()
+	hash.Write([]byte(requestID))
+	return float64(hash.Sum64())/float64(math.MaxUint64) < rate
+}
+
+var (
+	// Email addresses, including URL-encoded ones in query strings
+	emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*(@|%40)([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+
+	// Credential headers rendered as "Name: value" or "name=value"
+	credentialPattern = regexp.MustCompile(`(?i)((?:authorization|cookie)["']?\s*[:=]\s*["']?)[^"'\r\n,}]+`)
+)
+
+// Mask email local parts and credential header values in a log line
+func maskPII(line string) string {
+	line = emailPattern.ReplaceAllString(line, "$1***$2$3")
+	return credentialPattern.ReplaceAllString(line, "$1[REDACTED]")
+}
+
+// piiMaskingWriter scrubs each log line before writing it
+type piiMaskingWriter struct {
+	w io.Writer
+}
+
+func (p piiMaskingWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, maskPII(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Format an access log entry as a single JSON line
+func jsonLogFormatter(param gin.LogFormatterParams) string {
+	requestID, _ := param.Keys[requestIDKey].(string)
+
+	entry := map[string]interface{}{
+		"level":     "info",
+		"timestamp": param.TimeStamp.Format(time.RFC3339Nano),
+		"method":    param.Method,
+		"path":      param.Path,
+		"status":    param.StatusCode,
+		"latencyMs": float64(param.Latency.Microseconds()) / 1000,
+		"clientIp":  param.ClientIP,
+		"requestId": requestID,
+	}
+	if param.ErrorMessage != "" {
+		entry["error"] = param.ErrorMessage
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}\n", err.Error())
+	}
+	return string(line) + "\n"
+}
+
+// Log a warn-level line for requests slower than threshold, in the access
+// log's format, so slow handlers stand out
+func slowRequestLogger(threshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if threshold <= 0 {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		latency := time.Since(start)
+		if latency <= threshold {
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		var line string
+		if config.LogFormat == "json" {
+			encoded, _ := json.Marshal(map[string]interface{}{
+				"level":     "warn",
+				"msg":       "slow request",
+				"timestamp": time.Now().Format(time.RFC3339Nano),
+				"method":    c.Request.Method,
+				"route":     route,
+				"status":    c.Writer.Status(),
+				"latencyMs": float64(latency.Microseconds()) / 1000,
+				"requestId": c.GetString(requestIDKey),
+			})
+			line = string(encoded)
+		} else {
+			line = fmt.Sprintf("level=warn msg=\"slow request\" method=%s route=%s status=%d latency=%s requestId=%s",
+				c.Request.Method, route, c.Writer.Status(), latency, c.GetString(requestIDKey))
+		}
+		fmt.Fprintln(gin.DefaultWriter, line)
+	}
+}
+
+// Set once shutdown starts; new requests are turned away while in-flight
+// ones finish
+var draining atomic.Bool
+
+// Reject requests with 503 while the server is draining for shutdown
+func drainingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if draining.Load() {
+			c.Header("Connection", "close")
+			respondError(c, 503, "shutting_down", "Server is shutting down, please retry")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Set while API routes are down for planned maintenance; seeded from
+// MAINTENANCE_MODE and flipped by admins at runtime
+var maintenance atomic.Bool
+
+// Reject API requests with 503 during maintenance. Health checks and the
+// SPA stay up, as do login and the toggle itself so an admin can turn
+// maintenance back off.
+func maintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !maintenance.Load() || !strings.HasPrefix(path, apiPath("/api/")) ||
+			path == apiPath("/api/auth/login") || path == apiPath("/api/demo/maintenance") {
+			c.Next()
+			return
+		}
+		c.Header("Retry-After", "300")
+		respondError(c, 503, "maintenance", "The demo is down for planned maintenance, please try again shortly")
+	}
+}
+
+// Require a JSON Content-Type on API write requests that carry a body, so
+// form posts get a clear 415 instead of a confusing bind error. Bodyless
+// writes (e.g. logout) pass through.
+func requireJSONContentType() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+		if c.FullPath() == "" || c.Request.ContentLength == 0 || !strings.HasPrefix(c.Request.URL.Path, apiPath("/api/")) {
+			c.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			respondError(c, 415, "unsupported_media_type", "Content-Type must be application/json")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Reject requests whose Host header (ignoring any port) isn't one of
+// allowedHosts, guarding against host-header attacks. An empty list
+// disables the check.
+func allowedHostsMiddleware(allowedHosts []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedHosts))
+	for _, host := range allowedHosts {
+		allowed[strings.ToLower(host)] = true
+	}
+
+	return func(c *gin.Context) {
+		if len(allowed) == 0 {
+			c.Next()
+			return
+		}
+
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !allowed[strings.ToLower(host)] {
+			respondError(c, 400, "invalid_host", "Host header is not allowed")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Reject requests whose raw URI (path plus query) exceeds limit bytes
+func maxURILengthMiddleware(limit int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit > 0 && len(c.Request.RequestURI) > limit {
+			respondError(c, 414, "uri_too_long", fmt.Sprintf("Request URI exceeds %d bytes", limit))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Whether s contains a control character such as CR or LF; tabs are
+// allowed where allowTab is set, as header values may contain them
+func hasControlChars(s string, allowTab bool) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) && !(allowTab && r == '\t') {
+			return true
+		}
+	}
+	return false
+}
+
+// Reject requests whose header values, query parameters or path params
+// contain control characters, so CRLF sequences can't be smuggled into
+// logs or response headers
+func rejectControlCharsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for name, values := range c.Request.Header {
+			for _, value := range values {
+				if hasControlChars(value, true) {
+					respondError(c, 400, "invalid_characters", fmt.Sprintf("Header %s contains control characters", name))
+					return
+				}
+			}
+		}
+		for name, values := range c.Request.URL.Query() {
+			for _, value := range values {
+				if hasControlChars(name, false) || hasControlChars(value, false) {
+					respondError(c, 400, "invalid_characters", "Query parameters must not contain control characters")
+					return
+				}
+			}
+		}
+		for _, param := range c.Params {
+			if hasControlChars(param.Value, false) {
+				respondError(c, 400, "invalid_characters", fmt.Sprintf("Path parameter %s contains control characters", param.Key))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Bound request bodies so oversized payloads fail to bind instead of
+// being read into memory
+func maxBodyMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit > 0 && c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// Check whether a read/bind error was caused by the body size limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// Routes that stream or hold the connection open and are exempt from request
+// timeouts
+var longLivedRoutes = map[string]bool{
+	"/api/vortex/invitations/stream":                    true,
+	"/api/vortex/invitations/by-group/:type/:id/export": true,
+}
+
+// A route group's own timeout when set, otherwise REQUEST_TIMEOUT
+func routeGroupTimeout(groupTimeout time.Duration) time.Duration {
+	if groupTimeout > 0 {
+		return groupTimeout
+	}
+	return config.RequestTimeout
+}
+
+// Give each request a deadline; route groups wire it with their own
+// timeout. Handlers and downstream calls observe it via
+// c.Request.Context(). The handler writes into a buffer, so when the
+// deadline passes first the client gets a 503 with the error envelope right
+// away, even if the handler ignores its context, and anything it writes
+// afterwards is discarded. Flush on the buffer is a no-op, so every route
+// not in longLivedRoutes has its response held until the handler returns.
+func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 || longLivedRoutes[strings.TrimPrefix(c.FullPath(), config.APIPrefix)] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		// Read up front; the handler owns the context until it returns
+		requestID := c.GetString(requestIDKey)
+
+		original := c.Writer
+		buffered := newTimeoutWriter(original)
+		c.Writer = buffered
+
+		done := make(chan struct{})
+		var panicked interface{}
+		go func() {
+			defer func() {
+				panicked = recover()
+				buffered.finish()
+				close(done)
+			}()
+			c.Next()
+		}()
+
+		timedOut := false
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if timedOut = buffered.timeOut(); timedOut {
+				writeTimeoutResponse(original, requestID)
+			}
+			<-done
+		}
+		c.Writer = original
+
+		if timedOut {
+			if panicked != nil {
+				log.Printf("panic after timeout (request %s): %v", requestID, panicked)
+			}
+			return
+		}
+		if panicked != nil {
+			panic(panicked)
+		}
+		if !buffered.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			respondError(c, 503, "request_timeout", "Request timed out")
+			return
+		}
+		buffered.commit()
+	}
+}
+
+// Write the request_timeout envelope straight to the client
+func writeTimeoutResponse(w gin.ResponseWriter, requestID string) {
+	body := gin.H{"error": ErrorBody{Code: "request_timeout", Message: "Request timed out", RequestID: requestID}}
+	payload, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	w.WriteHeader(503)
+	w.Write(payload)
+	w.Flush()
+}
+
+// timeoutWriter buffers a handler's response so timeoutMiddleware can
+// replace it with a 503 if the deadline passes first
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	mu          sync.Mutex
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	finished    bool
+	timedOut    bool
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{ResponseWriter: w, header: w.Header().Clone(), status: http.StatusOK}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader && code > 0 {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wroteHeader
+}
+
+// Buffered until commit, so streaming handlers can't run behind this writer
+func (w *timeoutWriter) Flush() {}
+
+// Mark the handler as returned so a late deadline can't discard its response
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.finished = true
+}
+
+// Discard the response if the handler is still running; reports whether it
+// was
+func (w *timeoutWriter) timeOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.finished {
+		w.timedOut = true
+	}
+	return w.timedOut
+}
+
+// Copy the buffered response to the underlying writer
+func (w *timeoutWriter) commit() {
+	dst := w.ResponseWriter.Header()
+	for key := range dst {
+		delete(dst, key)
+	}
+	for key, values := range w.header {
+		dst[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	} else if w.wroteHeader {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Whether to indent this response: ?pretty= when it parses, otherwise
+// JSON_PRETTY
+func wantsPrettyJSON(c *gin.Context) bool {
+	if pretty, err := strconv.ParseBool(c.Query("pretty")); err == nil {
+		return pretty
+	}
+	return config.JSONPretty
+}
+
+// Indent JSON responses when wantsPrettyJSON
+func prettyJSONMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if wantsPrettyJSON(c) {
+			c.Writer = &prettyJSONWriter{ResponseWriter: c.Writer}
+		}
+		c.Next()
+	}
+}
+
+// prettyJSONWriter indents a JSON body on its way out. Gin renders JSON in a
+// single write, so only the first write is indented; later writes and
+// bodies that aren't one complete JSON value pass through unchanged.
+type prettyJSONWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *prettyJSONWriter) Write(data []byte) (int, error) {
+	if w.Written() || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+
+	// Same indentation as c.IndentedJSON
+	var indented bytes.Buffer
+	if json.Indent(&indented, data, "", "    ") != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	w.Header().Del("Content-Length")
+	if _, err := w.ResponseWriter.Write(indented.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *prettyJSONWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Hide a route behind a feature flag. Disabled features respond exactly like
+// an unknown route so they aren't advertised.
+func requireFeature(enabled func(Features) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled(config.Features) {
+			respondError(c, 404, "not_found", "Route not found")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Restrict the given paths to callers connecting directly from a trusted
+// proxy address; everyone else gets a 403
+func internalOnlyMiddleware(paths []string, trustedProxies []string) (gin.HandlerFunc, error) {
+	internal := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		internal[path] = true
+	}
+
+	networks, err := parseNetworks(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		if !internal[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		// RemoteIP is the direct peer, not the X-Forwarded-For client
+		ip := net.ParseIP(c.RemoteIP())
+		for _, network := range networks {
+			if ip != nil && network.Contains(ip) {
+				c.Next()
+				return
+			}
+		}
+		respondError(c, 403, "forbidden", "This endpoint is only available internally")
+	}, nil
+}
+
+// Parse IPs and CIDRs into networks; bare IPs become single-host networks
+func parseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Strip trailing slashes from API paths before Gin routes the request, so
+// /api/auth/me/ is served by the same handler as /api/auth/me. This has to
+// wrap the engine because Gin middleware runs after routing.
+func normalizeAPITrailingSlash(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.Path
+		apiRoot := apiPath("/api/")
+		if strings.HasPrefix(path, apiRoot) && len(path) > len(apiRoot) && strings.HasSuffix(path, "/") {
+			req.URL.Path = strings.TrimRight(path, "/")
+			if req.URL.RawPath != "" {
+				req.URL.RawPath = strings.TrimRight(req.URL.RawPath, "/")
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoveryReturnsErrorEnvelope(t *testing.T) {
+	router := gin.New()
+	router.Use(recoveryMiddleware(), requestIDMiddleware())
+	router.GET("/boom", func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest("GET", "/boom", nil)
+	req.Header.Set(requestIDHeader, "req-123")
+	rec := serve(router, req)
+	if rec.Code != 500 {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+
+	body := decodeErrorBody(t, rec)
+	if body.Code != "internal_error" || body.RequestID != "req-123" {
+		t.Fatalf("error = %+v, want internal_error for req-123", body)
+	}
+	if body.Details != nil {
+		t.Fatalf("details = %v, stack must not leak outside debug mode", body.Details)
+	}
+}
+
+func TestJSONLogFormatterEmitsOneJSONLine(t *testing.T) {
+	line := jsonLogFormatter(gin.LogFormatterParams{
+		TimeStamp:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Method:     "GET",
+		Path:       "/health",
+		StatusCode: 200,
+		Latency:    1500 * time.Microsecond,
+		ClientIP:   "203.0.113.7",
+		Keys:       map[string]any{requestIDKey: "req-1"},
+	})
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("log entry is not a single line: %q", line)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("decode %q: %v", line, err)
+	}
+	if entry["path"] != "/health" || entry["status"] != float64(200) || entry["latencyMs"] != 1.5 || entry["requestId"] != "req-1" {
+		t.Fatalf("entry = %v", entry)
+	}
+	if _, ok := entry["error"]; ok {
+		t.Fatal("error key present without an error")
+	}
+}
+
+func TestMaxBodyMiddlewareRejectsOversizedLogin(t *testing.T) {
+	router := gin.New()
+	router.Use(maxBodyMiddleware(64))
+	setupAuthRoutes(router)
+
+	payload := `{"email":"admin@example.com","password":"` + strings.Repeat("x", 128) + `"}`
+	rec := serve(router, httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(payload)))
+	if rec.Code != 413 || decodeErrorBody(t, rec).Code != "payload_too_large" {
+		t.Fatalf("status = %d, body = %s, want 413 payload_too_large", rec.Code, rec.Body.String())
+	}
+
+	payload = `{"email":"admin@example.com","password":"x"}`
+	if rec := serve(router, httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(payload))); rec.Code == 413 {
+		t.Fatal("small body rejected as too large")
+	}
+}
+func TestTimeoutRespondsWhileHandlerIgnoresContext(t *testing.T) {
+	release := make(chan struct{})
+	lateWrite := make(chan error, 1)
+
+	router := gin.New()
+	router.Use(requestIDMiddleware(), timeoutMiddleware(50*time.Millisecond))
+	router.GET("/api/slow", func(c *gin.Context) {
+		<-release
+		_, err := c.Writer.Write([]byte("late"))
+		lateWrite <- err
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	resp, err := http.Get(server.URL + "/api/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout response took %s", elapsed)
+	}
+	if resp.StatusCode != 503 {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+
+	var body struct {
+		Error ErrorBody `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != "request_timeout" || body.Error.RequestID == "" {
+		t.Errorf("error = %+v, want request_timeout with a request ID", body.Error)
+	}
+
+	release <- struct{}{}
+	if err := <-lateWrite; err != http.ErrHandlerTimeout {
+		t.Errorf("late write err = %v, want http.ErrHandlerTimeout", err)
+	}
+}
+
+func TestTimeoutPassesFastResponseThrough(t *testing.T) {
+	router := gin.New()
+	router.Use(timeoutMiddleware(time.Second))
+	router.POST("/api/fast", func(c *gin.Context) {
+		c.Header("Location", "/api/fast/1")
+		c.JSON(201, gin.H{"id": "1"})
+	})
+
+	rec := serve(router, httptest.NewRequest("POST", "/api/fast", nil))
+	if rec.Code != 201 {
+		t.Errorf("status = %d, want 201", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/api/fast/1" {
+		t.Errorf("Location = %q, want /api/fast/1", got)
+	}
+	if got := rec.Body.String(); got != `{"id":"1"}` {
+		t.Errorf("body = %s", got)
+	}
+}
+
+func TestInternalOnlyPathsRequireTrustedPeer(t *testing.T) {
+	internalOnly, err := internalOnlyMiddleware([]string{"/health"}, []string{"10.0.0.0/8", "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Use(internalOnly)
+	router.GET("/health", func(c *gin.Context) { c.Status(200) })
+	router.GET("/public", func(c *gin.Context) { c.Status(200) })
+
+	tests := []struct {
+		path, remote string
+		want         int
+	}{
+		{"/health", "10.1.2.3:5000", 200},
+		{"/health", "127.0.0.1:5000", 200},
+		{"/health", "203.0.113.7:5000", 403},
+		{"/public", "203.0.113.7:5000", 200},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = tt.remote
+		// A spoofed forwarding header must not grant access
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		if rec := serve(router, req); rec.Code != tt.want {
+			t.Errorf("%s from %s: status = %d, want %d", tt.path, tt.remote, rec.Code, tt.want)
+		}
+	}
+
+	if _, err := internalOnlyMiddleware(nil, []string{"not-an-ip"}); err == nil {
+		t.Error("invalid proxy address accepted")
+	}
+}
+
+func TestNormalizeAPITrailingSlash(t *testing.T) {
+	router := gin.New()
+	router.GET("/api/auth/me", func(c *gin.Context) { c.String(200, c.Request.URL.Path) })
+	handler := normalizeAPITrailingSlash(router)
+
+	for _, path := range []string{"/api/auth/me", "/api/auth/me/", "/api/auth/me//"} {
+		rec := serve(handler, httptest.NewRequest("GET", path, nil))
+		if rec.Code != 200 || rec.Body.String() != "/api/auth/me" {
+			t.Errorf("%s: status = %d, body = %q, want 200 /api/auth/me", path, rec.Code, rec.Body)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/static/", nil)
+	normalizeAPITrailingSlash(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/static/" {
+			t.Errorf("non-API path rewritten to %q", r.URL.Path)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestMaskPII(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"GET /api/users?email=alice@example.com", "GET /api/users?email=a***@example.com"},
+		{"GET /api/users?email=bob%40example.com", "GET /api/users?email=b***%40example.com"},
+		{"Authorization: Bearer abc.def", "Authorization: [REDACTED]"},
+		{`{"cookie":"session=xyz"}`, `{"cookie":"[REDACTED]"}`},
+		{"GET /health 200", "GET /health 200"},
+	}
+	for _, tt := range tests {
+		if got := maskPII(tt.line); got != tt.want {
+			t.Errorf("maskPII(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSlowRequestLoggerWarnsPastThreshold(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.LogFormat = "json" })
+	var logs bytes.Buffer
+	saved := gin.DefaultWriter
+	t.Cleanup(func() { gin.DefaultWriter = saved })
+	gin.DefaultWriter = &logs
+
+	router := gin.New()
+	router.Use(slowRequestLogger(10 * time.Millisecond))
+	router.GET("/fast", func(c *gin.Context) { c.Status(200) })
+	router.GET("/slow/:id", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.Status(200)
+	})
+
+	serve(router, httptest.NewRequest("GET", "/fast", nil))
+	if logs.Len() != 0 {
+		t.Fatalf("fast request logged: %s", logs.String())
+	}
+
+	serve(router, httptest.NewRequest("GET", "/slow/42", nil))
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("decode %q: %v", logs.String(), err)
+	}
+	if entry["level"] != "warn" || entry["route"] != "/slow/:id" || entry["status"] != float64(200) {
+		t.Errorf("entry = %v", entry)
+	}
+}
+
+func TestAccessLoggerSamplesOnlySuccesses(t *testing.T) {
+	useConfig(t, func(cfg *Config) {
+		cfg.LogSampleRate = 0
+		cfg.LogMaskPII = false
+	})
+	var logs bytes.Buffer
+	saved := gin.DefaultWriter
+	t.Cleanup(func() { gin.DefaultWriter = saved })
+	gin.DefaultWriter = &logs
+
+	router := gin.New()
+	router.Use(requestIDMiddleware(), accessLogger())
+	router.GET("/ok", func(c *gin.Context) { c.Status(200) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(500) })
+
+	serve(router, httptest.NewRequest("GET", "/ok", nil))
+	if logs.Len() != 0 {
+		t.Fatalf("2xx logged at rate 0: %s", logs.String())
+	}
+	serve(router, httptest.NewRequest("GET", "/fail", nil))
+	if !strings.Contains(logs.String(), "/fail") {
+		t.Errorf("5xx not logged: %q", logs.String())
+	}
+}
+
+func TestSampledIsDeterministic(t *testing.T) {
+	for _, id := range []string{"req-1", "req-2", "req-3"} {
+		if sampled(id, 0.5) != sampled(id, 0.5) {
+			t.Errorf("%s sampled inconsistently", id)
+		}
+		if !sampled(id, 1) || sampled(id, 0) {
+			t.Errorf("%s: rate 1 must keep and rate 0 must drop", id)
+		}
+	}
+}
+
+func corsRouter(origins []string) *gin.Engine {
+	router := gin.New()
+	router.Use(corsMiddleware(origins, 600, []string{requestIDHeader}))
+	router.GET("/api/ping", func(c *gin.Context) { c.Status(200) })
+	return router
+}
+
+func TestCORSWildcardOmitsCredentials(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/ping", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rec := serve(corsRouter([]string{"*"}), req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+}
+
+func TestCORSListedOriginGetsCredentials(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/ping", nil)
+	req.Header.Set("Origin", "https://app.example")
+	rec := serve(corsRouter([]string{"*", "https://app.example/"}), req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the origin", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != requestIDHeader {
+		t.Errorf("Access-Control-Expose-Headers = %q, want %s", got, requestIDHeader)
+	}
+}
+
+func TestCORSUnlistedOriginGetsNoHeaders(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/ping", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rec := serve(corsRouter([]string{"https://app.example"}), req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	req := httptest.NewRequest("OPTIONS", "/api/ping", nil)
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := serve(corsRouter([]string{"https://app.example"}), req)
+
+	if rec.Code != 204 {
+		t.Errorf("status = %d, want 204", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+	}
+}
+
+func TestDrainingRejectsNewRequests(t *testing.T) {
+	t.Cleanup(func() { draining.Store(false) })
+	router := gin.New()
+	router.Use(drainingMiddleware())
+	router.GET("/api/ping", func(c *gin.Context) { c.Status(200) })
+
+	if rec := serve(router, httptest.NewRequest("GET", "/api/ping", nil)); rec.Code != 200 {
+		t.Fatalf("before shutdown: status = %d, want 200", rec.Code)
+	}
+	draining.Store(true)
+	rec := serve(router, httptest.NewRequest("GET", "/api/ping", nil))
+	if rec.Code != 503 || !strings.Contains(rec.Body.String(), "shutting_down") {
+		t.Errorf("while draining: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Connection"); got != "close" {
+		t.Errorf("Connection = %q, want close", got)
+	}
+}
+
+func TestRequireJSONContentType(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	router.Use(requireJSONContentType())
+	router.POST("/api/things", func(c *gin.Context) { c.Status(200) })
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        int
+	}{
+		{"json", `{}`, "application/json; charset=utf-8", 200},
+		{"json suffix", `{}`, "application/merge-patch+json", 200},
+		{"form", "a=b", "application/x-www-form-urlencoded", 415},
+		{"missing", `{}`, "", 415},
+		{"no body", "", "", 200},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/things", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		if rec := serve(router, req); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestMaxURILengthRejectsLongURIs(t *testing.T) {
+	router := gin.New()
+	router.Use(maxURILengthMiddleware(64))
+	router.GET("/api/ping", func(c *gin.Context) { c.Status(200) })
+
+	if rec := serve(router, httptest.NewRequest("GET", "/api/ping?q=short", nil)); rec.Code != 200 {
+		t.Errorf("short URI: status = %d, want 200", rec.Code)
+	}
+	long := "/api/ping?q=" + strings.Repeat("x", 64)
+	rec := serve(router, httptest.NewRequest("GET", long, nil))
+	if rec.Code != 414 || !strings.Contains(rec.Body.String(), "uri_too_long") {
+		t.Errorf("long URI: status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAllowedHostsRejectsOtherHosts(t *testing.T) {
+	router := gin.New()
+	router.Use(allowedHostsMiddleware([]string{"Demo.example.com"}))
+	router.GET("/api/ping", func(c *gin.Context) { c.Status(200) })
+
+	tests := map[string]int{
+		"demo.example.com":      200,
+		"demo.example.com:8443": 200,
+		"evil.example.com":      400,
+	}
+	for host, want := range tests {
+		req := httptest.NewRequest("GET", "/api/ping", nil)
+		req.Host = host
+		if rec := serve(router, req); rec.Code != want {
+			t.Errorf("Host %s: status = %d, want %d", host, rec.Code, want)
+		}
+	}
+}
+
+func TestRouteGroupTimeoutOverridesRequestTimeout(t *testing.T) {
+	useConfig(t, func(cfg *Config) {
+		cfg.RequestTimeout = 50 * time.Millisecond
+		cfg.AuthRequestTimeout = 0
+		cfg.VortexRequestTimeout = time.Second
+	})
+	slow := func(c *gin.Context) {
+		time.Sleep(150 * time.Millisecond)
+		c.Status(200)
+	}
+
+	router := gin.New()
+	router.GET("/api/auth/slow", timeoutMiddleware(routeGroupTimeout(config.AuthRequestTimeout)), slow)
+	router.GET("/api/vortex/slow", timeoutMiddleware(routeGroupTimeout(config.VortexRequestTimeout)), slow)
+
+	if rec := serve(router, httptest.NewRequest("GET", "/api/auth/slow", nil)); rec.Code != 503 {
+		t.Errorf("auth: status = %d, want 503 from REQUEST_TIMEOUT", rec.Code)
+	}
+	if rec := serve(router, httptest.NewRequest("GET", "/api/vortex/slow", nil)); rec.Code != 200 {
+		t.Errorf("vortex: status = %d, want 200 within TIMEOUT_VORTEX", rec.Code)
+	}
+
+	config.AuthRequestTimeout = 20 * time.Millisecond
+	config.RequestTimeout = time.Second
+	router = gin.New()
+	router.GET("/api/auth/slow", timeoutMiddleware(routeGroupTimeout(config.AuthRequestTimeout)), slow)
+	if rec := serve(router, httptest.NewRequest("GET", "/api/auth/slow", nil)); rec.Code != 503 {
+		t.Errorf("auth: status = %d, want 503 from TIMEOUT_AUTH", rec.Code)
+	}
+}
+
+func TestMaintenanceResponsesCarryCORSHeaders(t *testing.T) {
+	useConfig(t, func(cfg *Config) {
+		cfg.CORSAllowedOrigins = []string{"https://app.example"}
+	})
+	maintenance.Store(true)
+	defer maintenance.Store(false)
+	savedWriter := gin.DefaultWriter
+	defer func() { gin.DefaultWriter = savedWriter }()
+	gin.DefaultWriter = io.Discard
+
+	router := gin.New()
+	router.Use(globalMiddleware()...)
+	router.GET("/api/ping", func(c *gin.Context) { c.Status(200) })
+	router.GET("/health", func(c *gin.Context) { c.Status(200) })
+
+	req := httptest.NewRequest("GET", "/api/ping", nil)
+	req.Header.Set("Origin", "https://app.example")
+	rec := serve(router, req)
+	if rec.Code != 503 {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the origin on the maintenance response", got)
+	}
+
+	if rec := serve(router, httptest.NewRequest("GET", "/health", nil)); rec.Code != 200 {
+		t.Errorf("/health status = %d, want 200 during maintenance", rec.Code)
+	}
+}
+
+func TestPrettyJSONIndentsOnRequest(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	router.Use(prettyJSONMiddleware())
+	router.GET("/api/ping", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+	body := func(query string) string {
+		return serve(router, httptest.NewRequest("GET", "/api/ping"+query, nil)).Body.String()
+	}
+
+	if got := body(""); got != `{"ok":true}` {
+		t.Errorf("default body = %q, want compact", got)
+	}
+	if got := body("?pretty=true"); got != "{\n    \"ok\": true\n}" {
+		t.Errorf("?pretty=true body = %q, want indented", got)
+	}
+
+	config.JSONPretty = true
+	if got := body(""); !strings.Contains(got, "\n    ") {
+		t.Errorf("JSON_PRETTY body = %q, want indented", got)
+	}
+	if got := body("?pretty=false"); got != `{"ok":true}` {
+		t.Errorf("?pretty=false body = %q, want compact", got)
+	}
+}
+
+func TestRejectControlCharsInHeadersQueryAndPath(t *testing.T) {
+	router := gin.New()
+	router.Use(rejectControlCharsMiddleware())
+	router.GET("/api/items/:id", func(c *gin.Context) { c.Status(200) })
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   int
+	}{
+		{"clean", "/api/items/a?q=b", "plain\tvalue", 200},
+		{"header CRLF", "/api/items/a", "x\r\nSet-Cookie: y", 400},
+		{"query LF", "/api/items/a?q=x%0Ay", "", 400},
+		{"path CR", "/api/items/a%0Db", "", 400},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		if tt.header != "" {
+			req.Header["X-Note"] = []string{tt.header}
+		}
+		rec := serve(router, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want == 400 && decodeErrorBody(t, rec).Code != "invalid_characters" {
+			t.Errorf("%s: body = %s, want invalid_characters", tt.name, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// vortexProbe tracks Vortex reachability for /ready. Readiness stays false
+// until the first probe succeeds, then follows the latest probe.
+type vortexProbe struct {
+	everSucceeded atomic.Bool
+
+	mu        sync.RWMutex
+	ok        bool
+	lastCheck time.Time
+	lastErr   error
+}
+
+var readiness = &vortexProbe{}
+
+// Probe Vortex once. Any HTTP response short of a 5xx proves it is
+// reachable; only the connection and server health matter here.
+func (p *vortexProbe) run() {
+	err := vortexRequest(context.Background(), "GET", "/", nil, nil)
+	ok := err == nil || classifyVortexError(err) == vortexErrRejected
+
+	p.mu.Lock()
+	p.ok = ok
+	p.lastCheck = time.Now()
+	p.lastErr = nil
+	if !ok {
+		p.lastErr = err
+	}
+	p.mu.Unlock()
+
+	if ok {
+		p.everSucceeded.Store(true)
+	}
+}
+
+// Probe immediately, then every interval for the life of the process
+func (p *vortexProbe) start(interval time.Duration) {
+	go func() {
+		p.run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			p.run()
+		}
+	}()
+}
+
+// Readiness: 503 until Vortex has answered a probe, then the result of the
+// latest one. Unlike /health this tells orchestrators whether to route
+// traffic here.
+func readyHandler(c *gin.Context) {
+	readiness.mu.RLock()
+	ok, lastCheck, lastErr := readiness.ok, readiness.lastCheck, readiness.lastErr
+	readiness.mu.RUnlock()
+
+	vortexStatus := gin.H{"reachable": ok}
+	if !lastCheck.IsZero() {
+		vortexStatus["lastCheck"] = formatTimestamp(lastCheck)
+	}
+	if lastErr != nil {
+		vortexStatus["error"] = errorText(lastErr)
+	}
+
+	if !readiness.everSucceeded.Load() || !ok {
+		c.JSON(503, gin.H{"status": "not_ready", "vortex": vortexStatus})
+		return
+	}
+	c.JSON(200, gin.H{"status": "ready", "vortex": vortexStatus})
+}
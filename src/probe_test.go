@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadyFollowsVortexProbe(t *testing.T) {
+	status := http.StatusNotFound
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+	saved := readiness
+	t.Cleanup(func() { readiness = saved })
+	readiness = &vortexProbe{}
+
+	router := gin.New()
+	router.GET("/ready", readyHandler)
+	ready := func() int { return serve(router, httptest.NewRequest("GET", "/ready", nil)).Code }
+
+	if got := ready(); got != 503 {
+		t.Errorf("before any probe: status = %d, want 503", got)
+	}
+
+	// A 4xx still proves Vortex is reachable
+	readiness.run()
+	if got := ready(); got != 200 {
+		t.Errorf("after a successful probe: status = %d, want 200", got)
+	}
+
+	status = http.StatusBadGateway
+	readiness.run()
+	if got := ready(); got != 503 {
+		t.Errorf("after a failed probe: status = %d, want 503", got)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestQuota caps requests per key over a sliding window by keeping the
+// time of each request still inside it
+type requestQuota struct {
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+var (
+	userQuotas   = &requestQuota{hits: make(map[string][]time.Time)}
+	targetQuotas = &requestQuota{hits: make(map[string][]time.Time)}
+)
+
+// Record a request for key unless limit requests already happened within
+// window. When over quota, returns false and how long until the oldest
+// request leaves the window.
+func (q *requestQuota) allow(key string, limit int, window time.Duration) (time.Duration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-window)
+	if now.Sub(q.lastSweep) >= window {
+		q.sweep(cutoff)
+		q.lastSweep = now
+	}
+
+	hits := q.hits[key]
+	for len(hits) > 0 && !hits[0].After(cutoff) {
+		hits = hits[1:]
+	}
+
+	if len(hits) >= limit {
+		q.hits[key] = hits
+		return hits[0].Add(window).Sub(now), false
+	}
+	q.hits[key] = append(hits, now)
+	return 0, true
+}
+
+// Drop keys whose latest request is at or before cutoff, so users and
+// targets that stop making requests don't stay in memory. Callers hold mu.
+func (q *requestQuota) sweep(cutoff time.Time) {
+	for key, hits := range q.hits {
+		if len(hits) == 0 || !hits[len(hits)-1].After(cutoff) {
+			delete(q.hits, key)
+		}
+	}
+}
+
+func (q *requestQuota) reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.hits = make(map[string][]time.Time)
+}
+
+// Enforce USER_QUOTA_PER_HOUR for an authenticated user, responding 429
+// with Retry-After when it is used up. Called by requireAuth, so
+// unauthenticated routes such as /health are never counted.
+func enforceUserQuota(c *gin.Context, userID string) bool {
+	limit := config.UserQuotaPerHour
+	if limit <= 0 {
+		return true
+	}
+
+	wait, ok := userQuotas.allow(userID, limit, time.Hour)
+	if !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondError(c, 429, "quota_exceeded", fmt.Sprintf("Request quota of %d per hour exceeded", limit))
+		return false
+	}
+	return true
+}
+
+// Enforce INVITES_PER_TARGET_PER_HOUR for an invitation target, keyed by
+// type and normalized value, so one address can't be flooded with
+// invitations. Responds 429 with Retry-After when the limit is reached.
+func enforceTargetInviteLimit(c *gin.Context, targetType, targetValue string) bool {
+	limit := config.InvitesPerTargetPerHour
+	if limit <= 0 {
+		return true
+	}
+
+	wait, ok := targetQuotas.allow(targetType+":"+targetValue, limit, time.Hour)
+	if !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondError(c, 429, "target_rate_limited", fmt.Sprintf("At most %d invitations per hour may be sent to the same target", limit))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+func TestUserQuotaThrottlesAuthenticatedRequests(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.UserQuotaPerHour = 2 })
+	t.Cleanup(userQuotas.reset)
+	router := gin.New()
+	setupDemoRoutes(router)
+	scopes := func(user DemoUser) *httptest.ResponseRecorder {
+		return serve(router, withSession(t, httptest.NewRequest("GET", "/api/demo/whoami-scopes", nil), user))
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := scopes(demoUsers[0]); rec.Code != 200 {
+			t.Fatalf("request %d: status = %d", i+1, rec.Code)
+		}
+	}
+	rec := scopes(demoUsers[0])
+	if rec.Code != 429 || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("over quota: status = %d, Retry-After = %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := scopes(demoUsers[1]); rec.Code != 200 {
+		t.Errorf("other user: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRequestQuotaSweepsIdleKeys(t *testing.T) {
+	q := &requestQuota{hits: make(map[string][]time.Time)}
+	window := 10 * time.Millisecond
+
+	q.allow("idle", 1, window)
+	time.Sleep(2 * window)
+	q.allow("active", 1, window)
+
+	if _, ok := q.hits["idle"]; ok || len(q.hits) != 1 {
+		t.Errorf("hits = %v, want only the active key", q.hits)
+	}
+}
+
+func TestTargetInviteLimitThrottlesFourthCreate(t *testing.T) {
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(vortex.InvitationResult{ID: "inv-new"})
+	})
+	config.InvitesPerTargetPerHour = 3
+	config.Features.DemoReset = true
+	useDemoUsers(t, demoUsers)
+	savedInitial := initialDemoUsers
+	t.Cleanup(func() { initialDemoUsers = savedInitial })
+	initialDemoUsers = demoUsers
+	t.Cleanup(targetQuotas.reset)
+
+	router := gin.New()
+	setupVortexRoutes(router)
+	setupDemoRoutes(router)
+	create := func(email string) *httptest.ResponseRecorder {
+		payload := `{"target":{"type":"email","value":"` + email + `"}}`
+		return serve(router, withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations", strings.NewReader(payload)), demoUsers[0]))
+	}
+
+	// Targets are counted after normalization, so case changes don't help
+	for _, email := range []string{"limit@example.com", "Limit@example.com", "LIMIT@example.com"} {
+		if rec := create(email); rec.Code != 201 {
+			t.Fatalf("%s: status = %d, body = %s", email, rec.Code, rec.Body)
+		}
+	}
+	rec := create("limit@example.com")
+	if rec.Code != 429 || rec.Header().Get("Retry-After") == "" || decodeErrorBody(t, rec).Code != "target_rate_limited" {
+		t.Errorf("fourth create: status = %d, Retry-After = %q, body = %s", rec.Code, rec.Header().Get("Retry-After"), rec.Body)
+	}
+	if rec := create("other@example.com"); rec.Code != 201 {
+		t.Errorf("other target: status = %d, want 201", rec.Code)
+	}
+
+	serve(router, httptest.NewRequest("POST", "/api/demo/reset", nil))
+	if rec := create("limit@example.com"); rec.Code != 201 {
+		t.Errorf("after reset: status = %d, want 201", rec.Code)
+	}
+}
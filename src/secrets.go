@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// secretSource resolves secret-typed settings such as VORTEX_API_KEY and
+// SESSION_JWT_SECRET. An empty value means the secret isn't set.
+type secretSource interface {
+	Secret(key string) (string, error)
+}
+
+// envSecretSource reads secrets like any other setting: flags, then the
+// environment, then CONFIG_FILE
+type envSecretSource struct{}
+
+func (envSecretSource) Secret(key string) (string, error) {
+	return lookupSetting(key), nil
+}
+
+// Secret sources selectable with SECRET_SOURCE. A secret-manager backend
+// registers itself from an init function in its own file, e.g.
+//
+//	func init() { registerSecretSource("aws", newAWSSecretSource) }
+var secretSources = map[string]func() (secretSource, error){
+	"env": func() (secretSource, error) { return envSecretSource{}, nil },
+}
+
+// Make a secret source available under name for SECRET_SOURCE
+func registerSecretSource(name string, factory func() (secretSource, error)) {
+	secretSources[name] = factory
+}
+
+// Source used by buildConfig for secret-typed settings
+var secrets secretSource = envSecretSource{}
+
+// Build the secret source named by SECRET_SOURCE (default "env")
+func newSecretSource(name string) (secretSource, error) {
+	if name == "" {
+		name = "env"
+	}
+	factory, ok := secretSources[name]
+	if !ok {
+		names := make([]string, 0, len(secretSources))
+		for registered := range secretSources {
+			names = append(names, registered)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown secret source %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return factory()
+}
+
+// Read a secret-typed setting. There is no safe fallback for a secret the
+// source failed to return, so startup stops.
+func lookupSecret(key string) string {
+	settingsRead[key] = true
+	value, err := secrets.Secret(key)
+	if err != nil {
+		log.Fatalf("Failed to read secret %s: %v", key, err)
+	}
+	return value
+}
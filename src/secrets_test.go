@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+type mapSecretSource map[string]string
+
+func (m mapSecretSource) Secret(key string) (string, error) {
+	return m[key], nil
+}
+
+func TestRegisteredSecretSourceSuppliesSecrets(t *testing.T) {
+	registerSecretSource("test", func() (secretSource, error) {
+		return mapSecretSource{"VORTEX_API_KEY": "from-manager"}, nil
+	})
+	t.Cleanup(func() { delete(secretSources, "test") })
+
+	source, err := newSecretSource("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := secrets
+	t.Cleanup(func() { secrets = saved })
+	secrets = source
+
+	if cfg := buildConfig(); cfg.VortexAPIKey != "from-manager" {
+		t.Errorf("VortexAPIKey = %q, want the secret source's value", cfg.VortexAPIKey)
+	}
+}
+
+func TestUnknownSecretSourceListsAvailable(t *testing.T) {
+	if _, err := newSecretSource("vault"); err == nil || !strings.Contains(err.Error(), "env") {
+		t.Errorf("err = %v, want unknown source listing env", err)
+	}
+	if source, err := newSecretSource(""); err != nil || source == nil {
+		t.Errorf("default source: %v, %v", source, err)
+	}
+}
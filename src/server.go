@@ -1,17 +1,46 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	jwtlib "github.com/golang-jwt/jwt/v5"
 	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
 )
 
 var vortexClient *vortex.Client
 
+// invitationClient is the subset of the Vortex SDK used by the invitation
+// handlers, kept as an interface so tests can substitute a fake
+type invitationClient interface {
+	GetInvitationsByTarget(targetType, targetValue string) ([]vortex.InvitationResult, error)
+	GetInvitation(invitationID string) (*vortex.InvitationResult, error)
+	RevokeInvitation(invitationID string) error
+	AcceptInvitations(invitationIDs []string, target vortex.InvitationTarget) (*vortex.InvitationResult, error)
+	GetInvitationsByGroup(groupType, groupID string) ([]vortex.InvitationResult, error)
+	DeleteInvitationsByGroup(groupType, groupID string) error
+	Reinvite(invitationID string) (*vortex.InvitationResult, error)
+}
+
+var vortexInvitations invitationClient
+
 // VortexConfig holds the configuration for Vortex integration
 type VortexConfig struct {
 	APIKey string
@@ -19,12 +48,19 @@ type VortexConfig struct {
 
 // Initialize Vortex client
 func initVortex() {
-	apiKey := os.Getenv("VORTEX_API_KEY")
+	apiKey := config.VortexAPIKey
 	if apiKey == "" {
 		apiKey = "demo-api-key"
 	}
 	vortexClient = vortex.NewClient(apiKey)
-	log.Printf("🔧 Vortex client initialized with API key: %s...", apiKey[:min(len(apiKey), 10)])
+	vortexInvitations = vortexClient
+	vortexAPIKey = apiKey
+	vortexHTTPClient = newVortexHTTPClient(config)
+	vortexSlots = make(chan struct{}, config.VortexMaxConcurrency)
+	invitationsByID.ttl = config.InvitationCacheTTL
+	keyPrefix := apiKey[:min(len(apiKey), 10)]
+	logEvent(levelInfo, "vortex", "client_initialized", map[string]interface{}{"apiKeyPrefix": keyPrefix},
+		fmt.Sprintf("🔧 Vortex client initialized with API key: %s...", keyPrefix))
 }
 
 func min(a, b int) int {
@@ -36,35 +72,70 @@ func min(a, b int) int {
 
 // Authentication routes
 func setupAuthRoutes(r *gin.Engine) {
-	auth := r.Group("/api/auth")
+	auth := r.Group(apiPath("/api/auth"), timeoutMiddleware(routeGroupTimeout(config.AuthRequestTimeout)))
 	{
 		auth.POST("/login", loginHandler)
 		auth.POST("/logout", logoutHandler)
+		auth.POST("/logout-all", requireAuth(), logoutAllHandler)
+		auth.GET("/sessions", requireAuth(), listSessionsHandler)
+		auth.DELETE("/sessions/:jti", requireAuth(), revokeSessionHandler)
+		auth.DELETE("/users/:id", requireAuth(), requireAutojoinAdmin(), deleteUserHandler)
 		auth.GET("/me", getMeHandler)
+		auth.POST("/impersonate/:userId", requireFeature(impersonationEnabled), requireAuth(), requireAutojoinAdmin(), impersonateHandler)
+		auth.POST("/stop-impersonating", requireFeature(impersonationEnabled), requireAuth(), stopImpersonatingHandler)
 	}
 }
 
 // Demo routes
 func setupDemoRoutes(r *gin.Engine) {
-	demo := r.Group("/api/demo")
+	demo := r.Group(apiPath("/api/demo"), timeoutMiddleware(config.RequestTimeout))
 	{
 		demo.GET("/users", getDemoUsersHandler)
 		demo.GET("/protected", requireAuth(), getProtectedHandler)
+		demo.GET("/protected-admin", requireAuth(), requireAutojoinAdmin(), getProtectedAdminHandler)
+		demo.GET("/features", getFeaturesHandler)
+		demo.GET("/whoami-scopes", requireAuth(), whoamiScopesHandler)
+		demo.POST("/echo", requireFeature(echoEnabled), echoHandler)
+		demo.GET("/stats", requireFeature(statsEnabled), requireAuth(), requireAutojoinAdmin(), getStatsHandler)
+		demo.POST("/reset", requireFeature(demoResetEnabled), resetDemoHandler)
+		demo.POST("/maintenance", requireAuth(), requireAutojoinAdmin(), setMaintenanceHandler)
 	}
 }
 
+// Feature flag accessors for requireFeature
+func impersonationEnabled(f Features) bool   { return f.Impersonation }
+func echoEnabled(f Features) bool            { return f.Echo }
+func statsEnabled(f Features) bool           { return f.Stats }
+func demoResetEnabled(f Features) bool       { return f.DemoReset }
+func simulatedErrorsEnabled(f Features) bool { return f.SimulatedErrors }
+
 // Vortex API routes
 func setupVortexRoutes(r *gin.Engine) {
-	vortexGroup := r.Group("/api/vortex")
+	vortexGroup := r.Group(apiPath("/api/vortex"), timeoutMiddleware(routeGroupTimeout(config.VortexRequestTimeout)))
 	{
 		vortexGroup.POST("/jwt", requireAuth(), generateJWTHandler)
-		vortexGroup.GET("/invitations", requireAuth(), getInvitationsHandler)
-		vortexGroup.GET("/invitations/:id", requireAuth(), getInvitationHandler)
-		vortexGroup.DELETE("/invitations/:id", requireAuth(), revokeInvitationHandler)
+		vortexGroup.GET("/jwt", requireAuth(), generateJWTHandler)
+		vortexGroup.GET("/jwt/decode", requireAuth(), requireAutojoinAdmin(), decodeJWTHandler)
+		vortexGroup.GET("/jwt/simulate-error", requireFeature(simulatedErrorsEnabled), requireAuth(), requireAutojoinAdmin(), simulateJWTErrorHandler)
+		vortexGroup.POST("/jwt/batch", requireAuth(), requireAutojoinAdmin(), batchGenerateJWTHandler)
+		vortexGroup.GET("/target-types", requireAuth(), getTargetTypesHandler)
+		vortexGroup.GET("/invitations", requireAuth(), validTargetQuery(), getInvitationsHandler)
+		vortexGroup.POST("/invitations", requireAuth(), createInvitationHandler)
+		vortexGroup.GET("/invitations/stream", requireAuth(), streamInvitationsHandler)
+		vortexGroup.GET("/invitations/:id", requireAuth(), validInvitationID(), getInvitationHandler)
+		vortexGroup.DELETE("/invitations/:id", requireAuth(), validInvitationID(), revokeInvitationHandler)
+		vortexGroup.PATCH("/invitations/:id", requireAuth(), validInvitationID(), updateInvitationMetadataHandler)
+		vortexGroup.GET("/invitations/:id/history", requireAuth(), validInvitationID(), getInvitationHistoryHandler)
 		vortexGroup.POST("/invitations/accept", requireAuth(), acceptInvitationsHandler)
-		vortexGroup.GET("/invitations/by-group/:type/:id", requireAuth(), getInvitationsByGroupHandler)
-		vortexGroup.DELETE("/invitations/by-group/:type/:id", requireAuth(), deleteInvitationsByGroupHandler)
-		vortexGroup.POST("/invitations/:id/reinvite", requireAuth(), reinviteHandler)
+		vortexGroup.POST("/invitations/batch-get", requireAuth(), batchGetInvitationsHandler)
+		vortexGroup.GET("/invitations/by-group/:type/:id", requireAuth(), validGroupParams(), getInvitationsByGroupHandler)
+		vortexGroup.DELETE("/invitations/by-group/:type/:id", requireAuth(), validGroupParams(), deleteInvitationsByGroupHandler)
+		vortexGroup.POST("/invitations/by-group/:type/:id/reinvite-all", requireAuth(), validGroupParams(), reinviteAllHandler)
+		vortexGroup.GET("/invitations/by-group/:type/:id/summary", requireAuth(), validGroupParams(), getGroupSummaryHandler)
+		vortexGroup.POST("/invitations/by-group/:type/:id/accept", requireAuth(), validGroupParams(), acceptGroupInvitationsHandler)
+		vortexGroup.POST("/invitations/by-group/:type/:id/prewarm", requireAuth(), requireAutojoinAdmin(), validGroupParams(), prewarmGroupHandler)
+		vortexGroup.GET("/invitations/by-group/:type/:id/export", requireAuth(), requireAutojoinAdmin(), validGroupParams(), exportGroupInvitationsHandler)
+		vortexGroup.POST("/invitations/:id/reinvite", requireAuth(), validInvitationID(), reinviteHandler)
 	}
 }
 
@@ -72,7 +143,7 @@ func setupVortexRoutes(r *gin.Engine) {
 func loginHandler(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Email and password required"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -89,16 +160,69 @@ func loginHandler(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie("session", sessionToken, 24*60*60, "/", "", false, true)
+	setSessionCookie(c, sessionToken)
 
 	c.JSON(200, LoginResponse{
 		Success: true,
-		User:    *user,
+		User:    userView(*user, wantsLegacyFields(c)),
+		Token:   sessionTokenForBody(sessionToken),
 	})
 }
 
 func logoutHandler(c *gin.Context) {
-	c.SetCookie("session", "", -1, "/", "", false, true)
+	// Revoke the current session so a copied token can't be reused
+	if user := getCurrentUser(c); user != nil && user.SessionID != "" {
+		sessions.revoke(user.SessionID)
+	}
+
+	clearSessionCookie(c)
+	c.JSON(200, gin.H{"success": true})
+}
+
+func logoutAllHandler(c *gin.Context) {
+	user := c.MustGet("user").(*DemoUser)
+	revoked := sessions.revokeAll(user.ID)
+
+	clearSessionCookie(c)
+	c.JSON(200, gin.H{"success": true, "revoked": revoked})
+}
+
+// SessionView describes one of the current user's active sessions
+type SessionView struct {
+	JTI       string    `json:"jti"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Current   bool      `json:"current"`
+}
+
+func listSessionsHandler(c *gin.Context) {
+	user := c.MustGet("user").(*DemoUser)
+
+	views := []SessionView{}
+	for _, record := range sessions.listForUser(user.ID) {
+		views = append(views, SessionView{
+			JTI:       record.JTI,
+			IssuedAt:  record.IssuedAt,
+			ExpiresAt: record.ExpiresAt,
+			Current:   record.JTI == user.SessionID,
+		})
+	}
+
+	c.JSON(200, gin.H{"sessions": views})
+}
+
+func revokeSessionHandler(c *gin.Context) {
+	user := c.MustGet("user").(*DemoUser)
+	jti := c.Param("jti")
+
+	if !sessions.revokeForUser(user.ID, jti) {
+		respondError(c, 404, "not_found", "Session not found")
+		return
+	}
+	if jti == user.SessionID {
+		clearSessionCookie(c)
+	}
+
 	c.JSON(200, gin.H{"success": true})
 }
 
@@ -109,7 +233,93 @@ func getMeHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{"user": user})
+	c.JSON(200, buildMeResponse(user, wantsLegacyFields(c)))
+}
+
+// Soft-delete a user and revoke their sessions
+func deleteUserHandler(c *gin.Context) {
+	admin := c.MustGet("user").(*DemoUser)
+	id := c.Param("id")
+
+	if id == admin.ID {
+		respondError(c, 400, "cannot_delete_self", "Admins can't delete their own account")
+		return
+	}
+	if !softDeleteDemoUser(id) {
+		respondError(c, 404, "user_not_found", "User not found")
+		return
+	}
+	revoked := sessions.revokeAll(id)
+
+	c.JSON(200, gin.H{"success": true, "revokedSessions": revoked})
+}
+
+func impersonateHandler(c *gin.Context) {
+	admin := c.MustGet("user").(*DemoUser)
+	if admin.ImpersonatedBy != "" {
+		respondError(c, 400, "already_impersonating", "Stop impersonating before impersonating another user")
+		return
+	}
+
+	target := findDemoUserByID(c.Param("userId"))
+	if target == nil {
+		respondError(c, 404, "user_not_found", "User not found")
+		return
+	}
+	if target.ID == admin.ID {
+		respondError(c, 400, "invalid_target", "Cannot impersonate yourself")
+		return
+	}
+
+	target.ImpersonatedBy = admin.ID
+	sessionToken, err := createSessionJWT(*target)
+	if err != nil {
+		respondError(c, 500, "session_failed", "Failed to create session token")
+		return
+	}
+
+	// The admin's own session is replaced; stop-impersonating issues a new one
+	sessions.revoke(admin.SessionID)
+	setSessionCookie(c, sessionToken)
+
+	response := gin.H{
+		"success":        true,
+		"user":           userView(*target, wantsLegacyFields(c)),
+		"impersonatedBy": admin.ID,
+	}
+	if token := sessionTokenForBody(sessionToken); token != "" {
+		response["token"] = token
+	}
+	c.JSON(200, response)
+}
+
+func stopImpersonatingHandler(c *gin.Context) {
+	user := c.MustGet("user").(*DemoUser)
+	if user.ImpersonatedBy == "" {
+		respondError(c, 400, "not_impersonating", "Not currently impersonating a user")
+		return
+	}
+
+	admin := findDemoUserByID(user.ImpersonatedBy)
+	if admin == nil {
+		respondError(c, 404, "user_not_found", "Impersonating admin no longer exists")
+		return
+	}
+
+	sessionToken, err := createSessionJWT(*admin)
+	if err != nil {
+		respondError(c, 500, "session_failed", "Failed to create session token")
+		return
+	}
+
+	sessions.revoke(user.SessionID)
+	setSessionCookie(c, sessionToken)
+
+	response := gin.H{"success": true, "user": userView(*admin, wantsLegacyFields(c))}
+	if token := sessionTokenForBody(sessionToken); token != "" {
+		response["token"] = token
+	}
+	c.JSON(200, response)
 }
 
 // Demo handlers
@@ -126,13 +336,145 @@ func getProtectedHandler(c *gin.Context) {
 
 	c.JSON(200, gin.H{
 		"message":   "This is a protected route!",
-		"user":      user,
-		"timestamp": time.Now().Format(time.RFC3339),
+		"user":      toPublicUser(*user.(*DemoUser)),
+		"timestamp": formatTimestamp(time.Now()),
+	})
+}
+
+func getFeaturesHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"features": config.Features,
+		"banner":   config.DemoBanner,
 	})
 }
 
+const maxEchoBodyBytes = 1 << 20
+
+// Headers never echoed back to the client
+var sensitiveHeaders = map[string]bool{
+	"Cookie":        true,
+	"Authorization": true,
+	"Set-Cookie":    true,
+	"X-Api-Key":     true,
+}
+
+func echoHandler(c *gin.Context) {
+	raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxEchoBodyBytes))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			respondError(c, 413, "payload_too_large", "Request body exceeds 1MB")
+			return
+		}
+		respondError(c, 400, "invalid_body", "Failed to read request body")
+		return
+	}
+
+	var body interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &body); err != nil {
+			respondError(c, 400, "invalid_json", "Request body must be valid JSON")
+			return
+		}
+	}
+
+	headers := make(map[string]string)
+	for name, values := range c.Request.Header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	c.JSON(200, gin.H{
+		"body":      body,
+		"headers":   headers,
+		"clientIp":  c.ClientIP(),
+		"requestId": c.GetString(requestIDKey),
+	})
+}
+
+func getProtectedAdminHandler(c *gin.Context) {
+	user := c.MustGet("user").(*DemoUser)
+
+	c.JSON(200, gin.H{
+		"message":   "This is an admin-only route!",
+		"user":      toPublicUser(*user),
+		"scopes":    vortexScopes(*user),
+		"timestamp": formatTimestamp(time.Now()),
+	})
+}
+
+// Demo users as they were at startup, restored by /api/demo/reset
+var initialDemoUsers []DemoUser
+
+// Restore demo state to how it was at startup, for integration tests
+func resetDemoHandler(c *gin.Context) {
+	demoUsersMu.Lock()
+	demoUsers = append([]DemoUser(nil), initialDemoUsers...)
+	count := len(demoUsers)
+	demoUsersMu.Unlock()
+
+	sessions.reset()
+	reinviteCooldowns.reset()
+	userQuotas.reset()
+	targetQuotas.reset()
+	invitationHistory.reset()
+	lastKnown.clear()
+	invitationsByID.clear()
+	lastGeneratedJWTs.Clear()
+
+	c.JSON(200, gin.H{"success": true, "users": count})
+}
+
+// Turn maintenance mode on or off; without a body it flips the current state
+func setMaintenanceHandler(c *gin.Context) {
+	enabled := !maintenance.Load()
+	if c.Request.ContentLength != 0 {
+		var req struct {
+			Enabled *bool `json:"enabled" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		enabled = *req.Enabled
+	}
+
+	maintenance.Store(enabled)
+	user := c.MustGet("user").(*DemoUser)
+	logEvent(levelInfo, "server", "maintenance_mode", map[string]interface{}{"enabled": enabled, "by": user.ID},
+		fmt.Sprintf("🔧 Maintenance mode set to %t by user %s", enabled, user.ID))
+
+	c.JSON(200, gin.H{"success": true, "maintenance": enabled})
+}
+
 // Vortex handlers
+
+// jwtGenerator is the part of the Vortex client that issues JWTs, so the
+// failure path can be exercised with a generator that always errors
+type jwtGenerator interface {
+	GenerateJWT(user *vortex.User, extra map[string]interface{}) (string, error)
+}
+
+// failingJWTGenerator stands in for Vortex in /jwt/simulate-error
+type failingJWTGenerator struct{}
+
+func (failingJWTGenerator) GenerateJWT(*vortex.User, map[string]interface{}) (string, error) {
+	return "", errors.New("simulated JWT generation failure")
+}
+
 func generateJWTHandler(c *gin.Context) {
+	respondWithJWT(c, vortexClient)
+}
+
+// Run the JWT handler against a failing generator so integrators can see
+// the error response
+func simulateJWTErrorHandler(c *gin.Context) {
+	respondWithJWT(c, failingJWTGenerator{})
+}
+
+// Generate a Vortex JWT for the current user with generator
+func respondWithJWT(c *gin.Context, generator jwtGenerator) {
 	user := getCurrentUser(c)
 	if user == nil {
 		c.JSON(401, gin.H{"error": "Authentication required"})
@@ -141,182 +483,966 @@ func generateJWTHandler(c *gin.Context) {
 
 	// Build user with admin scopes
 	vortexUser := &vortex.User{
-		ID:    user.ID,
-		Email: user.Email,
+		ID:          user.ID,
+		Email:       user.Email,
+		AdminScopes: vortexScopes(*user),
+	}
+
+	jwt, err := generator.GenerateJWT(vortexUser, nil)
+	if err != nil {
+		respondErrorCause(c, 500, "jwt_failed", "Failed to generate JWT", err)
+		return
 	}
 
+	lastGeneratedJWTs.Store(user.ID, jwt)
+
+	scopes := vortexUser.AdminScopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	// Expose expiry so clients can schedule a refresh
+	response := gin.H{"jwt": jwt, "scopes": scopes}
+	for claim, value := range jwtTimeClaims(jwt) {
+		response[claim] = value
+	}
+
+	c.JSON(200, response)
+}
+
+// Vortex admin scopes granted to a user's JWT; nil when there are none
+func vortexScopes(user DemoUser) []string {
 	if user.IsAutojoinAdmin {
-		vortexUser.AdminScopes = []string{"autojoin"}
+		return []string{"autojoin"}
+	}
+	return nil
+}
+
+// Preview the scopes a Vortex JWT would carry without issuing one
+func whoamiScopesHandler(c *gin.Context) {
+	scopes := vortexScopes(*getCurrentUser(c))
+	if scopes == nil {
+		scopes = []string{}
+	}
+	c.JSON(200, gin.H{"scopes": scopes})
+}
+
+const maxBatchJWTUsers = 200
+
+// BatchJWTUser is one user to mint a Vortex JWT for
+type BatchJWTUser struct {
+	ID              string `json:"id"`
+	Email           string `json:"email"`
+	IsAutojoinAdmin bool   `json:"isAutojoinAdmin"`
+}
+
+// Generate Vortex JWTs for many users; failures are reported per user
+// without affecting the rest
+func batchGenerateJWTHandler(c *gin.Context) {
+	var req struct {
+		Users []BatchJWTUser `json:"users" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if len(req.Users) > maxBatchJWTUsers {
+		respondError(c, 400, "too_many_users", fmt.Sprintf("At most %d users may be requested at once", maxBatchJWTUsers))
+		return
+	}
+
+	results := make(map[string]BatchItemResult, len(req.Users))
+	for i, entry := range req.Users {
+		// Entries without an ID are reported by position
+		if entry.ID == "" {
+			results[fmt.Sprintf("#%d", i)] = batchFailed("id is required")
+			continue
+		}
+		if entry.Email == "" {
+			results[entry.ID] = batchFailed("email is required")
+			continue
+		}
+
+		user := DemoUser{ID: entry.ID, Email: entry.Email, IsAutojoinAdmin: entry.IsAutojoinAdmin}
+		jwt, err := vortexClient.GenerateJWT(&vortex.User{
+			ID:          user.ID,
+			Email:       user.Email,
+			AdminScopes: vortexScopes(user),
+		}, nil)
+		if err != nil {
+			results[entry.ID] = batchFailed(errorText(err))
+			continue
+		}
+		results[entry.ID] = batchOK(gin.H{"jwt": jwt})
+	}
+
+	respondBatch(c, results)
+}
+
+// Most recent Vortex JWT generated per user ID, for /jwt/decode
+var lastGeneratedJWTs sync.Map
+
+// Decode a Vortex JWT's header and claims without verifying its signature.
+// Uses ?token= or, when absent, the caller's last generated JWT.
+func decodeJWTHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		if last, ok := lastGeneratedJWTs.Load(getCurrentUser(c).ID); ok {
+			token = last.(string)
+		}
+	}
+	if token == "" {
+		respondError(c, 400, "missing_token", "token query parameter required (no JWT generated yet)")
+		return
 	}
 
-	jwt, err := vortexClient.GenerateJWT(vortexUser, nil)
+	claims := jwtlib.MapClaims{}
+	parsed, _, err := jwtlib.NewParser(jwtlib.WithJSONNumber()).ParseUnverified(token, claims)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to generate JWT"})
+		respondError(c, 400, "invalid_token", "token is not a valid JWT")
 		return
 	}
 
-	c.JSON(200, gin.H{"jwt": jwt})
+	c.JSON(200, gin.H{
+		"verified": false,
+		"note":     "Decoded without signature verification; do not trust these claims",
+		"header":   parsed.Header,
+		"claims":   claims,
+	})
+}
+
+// Read the exp/iat claims from a JWT without verifying it. Returns an empty
+// map when the token can't be decoded or has no such claims.
+func jwtTimeClaims(token string) map[string]int64 {
+	times := make(map[string]int64)
+
+	claims := jwtlib.MapClaims{}
+	if _, _, err := jwtlib.NewParser().ParseUnverified(token, claims); err != nil {
+		return times
+	}
+
+	for _, name := range []string{"exp", "iat"} {
+		if value, ok := claims[name].(float64); ok {
+			times[name] = int64(value)
+		}
+	}
+	return times
+}
+
+// List the target types invitations accept, in allowedTargetTypes order,
+// so a frontend can offer them in a dropdown
+func getTargetTypesHandler(c *gin.Context) {
+	types := make([]gin.H, 0, len(allowedTargetTypes))
+	for _, targetType := range allowedTargetTypes {
+		types = append(types, gin.H{"type": targetType, "description": targetTypeDescriptions[targetType]})
+	}
+	c.JSON(200, gin.H{"targetTypes": types})
 }
 
 func getInvitationsHandler(c *gin.Context) {
 	targetType := c.Query("targetType")
-	targetValue := c.Query("targetValue")
+	targetValue := c.GetString(targetValueKey)
 
 	if targetType == "" || targetValue == "" {
 		c.JSON(400, gin.H{"error": "targetType and targetValue query parameters required"})
 		return
 	}
 
-	invitations, err := vortexClient.GetInvitationsByTarget(targetType, targetValue)
+	cacheKey := "target:" + targetType + ":" + targetValue
+	invitations, stale, ok := listWithFallback(c, cacheKey, "Failed to get invitations", func() ([]vortex.InvitationResult, error) {
+		return limitVortex(func() ([]vortex.InvitationResult, error) {
+			return vortexInvitations.GetInvitationsByTarget(targetType, targetValue)
+		})
+	})
+	if !ok {
+		return
+	}
+
+	invitations, nextCursor, ok := applyInvitationListQuery(c, invitations)
+	if !ok {
+		return
+	}
+
+	respondInvitationList(c, invitations, nextCursor, stale)
+}
+
+// Fetch an invitation list, falling back to the last known result while
+// Vortex is unreachable. Responds with an error and returns ok=false when
+// neither is available.
+func listWithFallback(c *gin.Context, cacheKey, failureMessage string, fetch func() ([]vortex.InvitationResult, error)) (invitations []vortex.InvitationResult, stale bool, ok bool) {
+	invitations, err := fetch()
+	if err == nil {
+		lastKnown.store(cacheKey, invitations)
+		return invitations, false, true
+	}
+
+	if cached, found := lastKnown.onOutage(cacheKey, err); found {
+		return cached.([]vortex.InvitationResult), true, true
+	}
+	respondVortexError(c, err, "list_failed", failureMessage)
+	return nil, false, false
+}
+
+// Respond with an invitation list, flagging data served from the fallback
+// cache and including the cursor for the next page when there is one
+func respondInvitationList(c *gin.Context, invitations []vortex.InvitationResult, nextCursor string, stale bool) {
+	// Lists are per-user, so they aren't cached unless configured otherwise
+	c.Header("Cache-Control", config.InvitationListCacheControl)
+
+	response := gin.H{"invitations": invitationViews(invitations, time.Now())}
+	if nextCursor != "" {
+		response["nextCursor"] = nextCursor
+	}
+	if stale {
+		response["stale"] = true
+	}
+	c.JSON(200, response)
+}
+
+func createInvitationHandler(c *gin.Context) {
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if isBodyTooLarge(err) {
+			respondError(c, 413, "payload_too_large", "Request body too large")
+			return
+		}
+		respondError(c, 400, "invalid_body", "Invalid request body")
+		return
+	}
+
+	req.Target.Value = normalizeTargetValue(req.Target.Type, req.Target.Value)
+	if err := validateInvitationTarget(req.Target); err != nil {
+		respondError(c, 400, "invalid_target", err.Error())
+		return
+	}
+	if !enforceTargetInviteLimit(c, req.Target.Type, req.Target.Value) {
+		return
+	}
+
+	invitation, err := createInvitation(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to get invitations"})
+		respondVortexError(c, err, "create_failed", "Failed to create invitation")
 		return
 	}
+	recordInvitationHistory(c, "created", invitation.ID)
 
-	c.JSON(200, gin.H{"invitations": invitations})
+	publishInvitationGroupEvents("created", invitation, []string{invitation.ID})
+
+	c.JSON(201, invitation)
 }
 
 func getInvitationHandler(c *gin.Context) {
 	id := c.Param("id")
 
-	invitation, err := vortexClient.GetInvitation(id)
+	cacheKey := "invitation:" + id
+	invitation, err := fetchInvitation(id)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Invitation not found"})
+		if cached, ok := lastKnown.onOutage(cacheKey, err); ok {
+			c.JSON(200, gin.H{"invitation": cached, "stale": true})
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(404, gin.H{"error": "Invitation not found"})
+			return
+		}
+		respondVortexError(c, err, "get_failed", "Failed to get invitation")
 		return
 	}
+	lastKnown.store(cacheKey, invitation)
 
-	c.JSON(200, invitation)
+	body, err := json.Marshal(invitation)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to encode invitation"})
+		return
+	}
+
+	// Weak ETag so polling clients can revalidate without re-downloading
+	etag := weakETag(body)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(304)
+		return
+	}
+
+	c.Data(200, "application/json; charset=utf-8", body)
+}
+
+// Compute a weak ETag from a serialized response body
+func weakETag(body []byte) string {
+	hash := sha256.Sum256(body)
+	return fmt.Sprintf(`W/"%x"`, hash[:16])
+}
+
+// Check whether an If-None-Match header value matches the given ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+const maxBatchGetIDs = 100
+
+func batchGetInvitationsHandler(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if isBodyTooLarge(err) {
+			respondError(c, 413, "payload_too_large", "Request body too large")
+			return
+		}
+		respondError(c, 400, "invalid_body", "Request body must include an ids array")
+		return
+	}
+
+	if len(req.IDs) > maxBatchGetIDs {
+		respondError(c, 400, "too_many_ids", fmt.Sprintf("At most %d ids may be requested at once", maxBatchGetIDs))
+		return
+	}
+
+	ids := slices.Compact(slices.Sorted(slices.Values(req.IDs)))
+	outcomes := make([]BatchItemResult, len(ids))
+	runBatch(len(ids), func(i int) {
+		invitation, err := fetchInvitation(ids[i])
+		switch {
+		case errors.Is(err, ErrNotFound) || (err == nil && invitation == nil):
+			outcomes[i] = BatchItemResult{Status: batchStatusNotFound}
+		case err != nil:
+			outcomes[i] = batchFailed(errorText(err))
+		default:
+			outcomes[i] = batchOK(invitation)
+		}
+	})
+
+	results := make(map[string]BatchItemResult, len(ids))
+	for i, id := range ids {
+		results[id] = outcomes[i]
+	}
+	respondBatch(c, results)
+}
+
+// Lifecycle of an invitation: its creation plus the changes made to it
+// through this server
+func getInvitationHistoryHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	invitation, err := fetchInvitation(id)
+	if errors.Is(err, ErrNotFound) || (err == nil && invitation == nil) {
+		respondError(c, 404, "not_found", "Invitation not found")
+		return
+	}
+	if err != nil {
+		respondVortexError(c, err, "get_failed", "Failed to get invitation")
+		return
+	}
+
+	c.JSON(200, gin.H{"invitationId": id, "events": buildInvitationHistory(id, invitation.CreatedAt)})
 }
 
 func revokeInvitationHandler(c *gin.Context) {
 	id := c.Param("id")
 
-	err := vortexClient.RevokeInvitation(id)
+	err := limitVortexErr(func() error {
+		return vortexInvitations.RevokeInvitation(id)
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to revoke invitation"})
+		respondVortexError(c, err, "revoke_failed", "Failed to revoke invitation")
 		return
 	}
+	invitationsByID.forget(id)
+	recordInvitationHistory(c, "revoked", id)
 
 	c.JSON(200, gin.H{"success": true})
 }
 
+func updateInvitationMetadataHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	var metadata map[string]interface{}
+	if err := c.ShouldBindJSON(&metadata); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if len(metadata) == 0 {
+		respondError(c, 400, "empty_metadata", "Body must be a non-empty metadata object")
+		return
+	}
+
+	invitation, err := updateInvitationMetadata(c.Request.Context(), id, metadata)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			respondError(c, 404, "not_found", "Invitation not found")
+			return
+		}
+		respondVortexError(c, err, "update_failed", "Failed to update invitation")
+		return
+	}
+
+	lastKnown.store("invitation:"+id, invitation)
+	invitationsByID.put(id, invitation)
+	recordInvitationHistory(c, "metadata_updated", id)
+	c.JSON(200, invitation)
+}
+
 func acceptInvitationsHandler(c *gin.Context) {
 	var req struct {
-		InvitationIDs []string             `json:"invitationIds" binding:"required"`
+		InvitationIDs []string                `json:"invitationIds" binding:"required"`
 		Target        vortex.InvitationTarget `json:"target" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request body"})
+		respondBindError(c, err)
+		return
+	}
+
+	// Catch unknown target types here rather than as an opaque SDK failure
+	if !isAllowedTargetType(req.Target.Type) {
+		respondErrorAllowed(c, 400, "invalid_target_type", fmt.Sprintf("Unsupported target type %q", req.Target.Type), allowedTargetTypes)
 		return
 	}
 
-	result, err := vortexClient.AcceptInvitations(req.InvitationIDs, req.Target)
+	// Check the redirect before accepting so a rejected one changes nothing
+	var redirect string
+	if raw := c.Query("redirect"); raw != "" {
+		var err error
+		redirect, err = sanitizeAcceptRedirect(raw, config.AcceptRedirectAllowlist)
+		if err != nil {
+			respondError(c, 400, "invalid_redirect", err.Error())
+			return
+		}
+	}
+
+	req.Target.Value = normalizeTargetValue(req.Target.Type, req.Target.Value)
+	result, err := limitVortex(func() (*vortex.InvitationResult, error) {
+		return vortexInvitations.AcceptInvitations(req.InvitationIDs, req.Target)
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to accept invitations"})
+		// A concurrent accept got there first
+		if errors.Is(err, ErrConflict) {
+			respondError(c, 409, "already_accepted", "Invitation has already been accepted")
+			return
+		}
+		respondVortexError(c, err, "accept_failed", "Failed to accept invitations")
 		return
 	}
+	invitationsByID.forget(req.InvitationIDs...)
+	recordInvitationHistory(c, "accepted", req.InvitationIDs...)
 
-	c.JSON(200, result)
+	publishInvitationGroupEvents("accepted", result, req.InvitationIDs)
+
+	c.JSON(200, struct {
+		*vortex.InvitationResult
+		Redirect string `json:"redirect,omitempty"`
+	}{result, redirect})
 }
 
 func getInvitationsByGroupHandler(c *gin.Context) {
 	groupType := c.Param("type")
 	groupID := c.Param("id")
 
-	invitations, err := vortexClient.GetInvitationsByGroup(groupType, groupID)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to get group invitations"})
+	cacheKey := "group:" + groupType + ":" + groupID
+	invitations, stale, ok := listWithFallback(c, cacheKey, "Failed to get group invitations", func() ([]vortex.InvitationResult, error) {
+		return limitVortex(func() ([]vortex.InvitationResult, error) {
+			return vortexInvitations.GetInvitationsByGroup(groupType, groupID)
+		})
+	})
+	if !ok {
+		return
+	}
+
+	invitations, nextCursor, ok := applyInvitationListQuery(c, invitations)
+	if !ok {
 		return
 	}
 
-	c.JSON(200, gin.H{"invitations": invitations})
+	respondInvitationList(c, invitations, nextCursor, stale)
 }
 
 func deleteInvitationsByGroupHandler(c *gin.Context) {
 	groupType := c.Param("type")
 	groupID := c.Param("id")
 
-	err := vortexClient.DeleteInvitationsByGroup(groupType, groupID)
+	err := limitVortexErr(func() error {
+		return vortexInvitations.DeleteInvitationsByGroup(groupType, groupID)
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete group invitations"})
+		respondVortexError(c, err, "delete_failed", "Failed to delete group invitations")
 		return
 	}
 
+	// The deleted IDs aren't known here, so drop the whole cache
+	invitationsByID.clear()
+	publishInvitationEvent("deleted", groupType, groupID, nil)
+
 	c.JSON(200, gin.H{"success": true})
 }
 
+// Fetch a group's invitations and seed the per-ID cache with them, so a
+// dashboard's single-invitation reads that follow skip Vortex
+func prewarmGroupHandler(c *gin.Context) {
+	groupType := c.Param("type")
+	groupID := c.Param("id")
+
+	if config.InvitationCacheTTL <= 0 {
+		respondError(c, 409, "cache_disabled", "The invitation cache is disabled (INVITATION_CACHE_TTL=0)")
+		return
+	}
+
+	invitations, err := limitVortex(func() ([]vortex.InvitationResult, error) {
+		return vortexInvitations.GetInvitationsByGroup(groupType, groupID)
+	})
+	if err != nil {
+		respondVortexError(c, err, "list_failed", "Failed to get group invitations")
+		return
+	}
+
+	for i := range invitations {
+		invitationsByID.put(invitations[i].ID, &invitations[i])
+	}
+
+	c.JSON(200, gin.H{"warmed": len(invitations), "ttl": config.InvitationCacheTTL.String()})
+}
+
+// Accept every pending invitation in a group in one call
+func acceptGroupInvitationsHandler(c *gin.Context) {
+	groupType := c.Param("type")
+	groupID := c.Param("id")
+
+	invitations, err := limitVortex(func() ([]vortex.InvitationResult, error) {
+		return vortexInvitations.GetInvitationsByGroup(groupType, groupID)
+	})
+	if err != nil {
+		respondVortexError(c, err, "list_failed", "Failed to get group invitations")
+		return
+	}
+
+	var ids []string
+	for _, invitation := range invitations {
+		if InvitationStatus(invitation.Status) == InvitationStatusPending {
+			ids = append(ids, invitation.ID)
+		}
+	}
+	if len(ids) == 0 {
+		respondError(c, 404, "not_found", "No pending invitations for this group")
+		return
+	}
+
+	target := vortex.InvitationTarget{Type: groupType, Value: groupID}
+	result, err := limitVortex(func() (*vortex.InvitationResult, error) {
+		return vortexInvitations.AcceptInvitations(ids, target)
+	})
+	if err != nil {
+		respondVortexError(c, err, "accept_failed", "Failed to accept invitations")
+		return
+	}
+	invitationsByID.forget(ids...)
+	recordInvitationHistory(c, "accepted", ids...)
+
+	publishInvitationEvent("accepted", groupType, groupID, ids)
+	c.JSON(200, result)
+}
+
 func reinviteHandler(c *gin.Context) {
 	id := c.Param("id")
 
-	result, err := vortexClient.Reinvite(id)
+	template := c.Query("template")
+	if template != "" && !slices.Contains(config.ReinviteTemplates, template) {
+		respondErrorAllowed(c, 400, "invalid_template", fmt.Sprintf("Unknown email template %q", template), config.ReinviteTemplates)
+		return
+	}
+
+	// Suppress duplicate emails from repeated clicks
+	if config.ReinviteCooldown > 0 {
+		wait, ok := reinviteCooldowns.claim(id, config.ReinviteCooldown)
+		if !ok {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			respondError(c, 429, "reinvite_cooldown", fmt.Sprintf("Invitation was just reinvited; retry in %ds", retryAfter))
+			return
+		}
+	}
+
+	// reinviteWithTemplate goes through vortexRequest, which takes its own
+	// concurrency slot
+	var result *vortex.InvitationResult
+	var err error
+	if template != "" {
+		result, err = reinviteWithTemplate(c.Request.Context(), id, template)
+	} else {
+		result, err = limitVortex(func() (*vortex.InvitationResult, error) {
+			return vortexInvitations.Reinvite(id)
+		})
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to reinvite"})
+		reinviteCooldowns.release(id)
+		respondVortexError(c, err, "reinvite_failed", "Failed to reinvite")
 		return
 	}
+	invitationsByID.forget(id)
+	recordInvitationHistory(c, "reinvited", id)
+
+	publishInvitationGroupEvents("reinvited", result, []string{id})
 
 	c.JSON(200, result)
 }
 
+func reinviteAllHandler(c *gin.Context) {
+	groupType := c.Param("type")
+	groupID := c.Param("id")
+
+	invitations, err := limitVortex(func() ([]vortex.InvitationResult, error) {
+		return vortexInvitations.GetInvitationsByGroup(groupType, groupID)
+	})
+	if err != nil {
+		respondVortexError(c, err, "list_failed", "Failed to get group invitations")
+		return
+	}
+
+	outcomes := make([]BatchItemResult, len(invitations))
+	runBatch(len(invitations), func(i int) {
+		// Accepted invitations have nothing left to resend
+		if InvitationStatus(invitations[i].Status) == InvitationStatusAccepted {
+			outcomes[i] = BatchItemResult{Status: batchStatusSkipped}
+			return
+		}
+
+		_, err := limitVortex(func() (*vortex.InvitationResult, error) {
+			return vortexInvitations.Reinvite(invitations[i].ID)
+		})
+		if err != nil {
+			outcomes[i] = batchFailed(errorText(err))
+			return
+		}
+		outcomes[i] = batchOK(nil)
+	})
+
+	results := make(map[string]BatchItemResult, len(invitations))
+	var reinvitedIDs []string
+	for i, invitation := range invitations {
+		results[invitation.ID] = outcomes[i]
+		if outcomes[i].Status == batchStatusOK {
+			reinvitedIDs = append(reinvitedIDs, invitation.ID)
+		}
+	}
+	invitationsByID.forget(reinvitedIDs...)
+	recordInvitationHistory(c, "reinvited", reinvitedIDs...)
+
+	if len(reinvitedIDs) > 0 {
+		publishInvitationEvent("reinvited", groupType, groupID, reinvitedIDs)
+	}
+
+	respondBatch(c, results)
+}
+
+// Export lines written between flushes
+const exportFlushEvery = 100
+
+// Stream a group's invitations as newline-delimited JSON, one invitation
+// per line, flushing as it goes so clients can process the export
+// incrementally
+func exportGroupInvitationsHandler(c *gin.Context) {
+	groupType := c.Param("type")
+	groupID := c.Param("id")
+
+	invitations, err := limitVortex(func() ([]vortex.InvitationResult, error) {
+		return vortexInvitations.GetInvitationsByGroup(groupType, groupID)
+	})
+	if err != nil {
+		respondVortexError(c, err, "list_failed", "Failed to get group invitations")
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "invitations-"+groupType+"-"+groupID+".ndjson"))
+	c.Status(200)
+
+	encoder := json.NewEncoder(c.Writer)
+	for i, invitation := range invitations {
+		// The status line is already sent, so a failed write just ends the stream
+		if err := encoder.Encode(invitation); err != nil {
+			return
+		}
+		if (i+1)%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+	c.Writer.Flush()
+}
+
+const sseKeepAliveInterval = 15 * time.Second
+
+func streamInvitationsHandler(c *gin.Context) {
+	groupType := c.Query("groupType")
+	groupID := c.Query("groupId")
+
+	if groupType == "" || groupID == "" {
+		respondError(c, 400, "invalid_query", "groupType and groupId query parameters required")
+		return
+	}
+
+	events, unsubscribe := invitationEvents.subscribe(groupType, groupID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(200)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event := <-events:
+			c.SSEvent("invitation", event)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func getGroupSummaryHandler(c *gin.Context) {
+	groupType := c.Param("type")
+	groupID := c.Param("id")
+
+	cacheKey := "group:" + groupType + ":" + groupID
+	invitations, stale, ok := listWithFallback(c, cacheKey, "Failed to get group invitations", func() ([]vortex.InvitationResult, error) {
+		return limitVortex(func() ([]vortex.InvitationResult, error) {
+			return vortexInvitations.GetInvitationsByGroup(groupType, groupID)
+		})
+	})
+	if !ok {
+		return
+	}
+
+	response := gin.H{
+		"groupType": groupType,
+		"groupId":   groupID,
+		"counts":    countInvitationsByStatus(invitations),
+		"total":     len(invitations),
+	}
+	if stale {
+		response["stale"] = true
+	}
+	c.JSON(200, response)
+}
+
 func healthHandler(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"status":    "healthy",
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp": formatTimestamp(time.Now()),
+		"build":     buildInfo(),
 		"vortex": gin.H{
 			"configured": true,
 			"routes": []string{
-				"/api/vortex/jwt",
-				"/api/vortex/invitations",
-				"/api/vortex/invitations/:id",
-				"/api/vortex/invitations/accept",
-				"/api/vortex/invitations/by-group/:type/:id",
-				"/api/vortex/invitations/:id/reinvite",
+				apiPath("/api/vortex/jwt"),
+				apiPath("/api/vortex/jwt/decode"),
+				apiPath("/api/vortex/jwt/batch"),
+				apiPath("/api/vortex/target-types"),
+				apiPath("/api/vortex/invitations"),
+				apiPath("/api/vortex/invitations/:id"),
+				apiPath("/api/vortex/invitations/accept"),
+				apiPath("/api/vortex/invitations/by-group/:type/:id"),
+				apiPath("/api/vortex/invitations/:id/reinvite"),
+				apiPath("/api/vortex/invitations/by-group/:type/:id/reinvite-all"),
+				apiPath("/api/vortex/invitations/by-group/:type/:id/summary"),
+				apiPath("/api/vortex/invitations/by-group/:type/:id/accept"),
 			},
 		},
 	})
 }
 
+// Serve the SPA index for unknown non-API GETs, JSON 404 otherwise
+func noRouteHandler(indexFile string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		isAPI := path == apiPath("/api") || strings.HasPrefix(path, apiPath("/api/"))
+		if isAPI || (c.Request.Method != "GET" && c.Request.Method != "HEAD") {
+			respondError(c, 404, "not_found", "Route not found")
+			return
+		}
+
+		c.File(indexFile)
+	}
+}
+
+// Respond 405 in the error envelope, naming the methods the route accepts
+func noMethodHandler(c *gin.Context) {
+	allowed := c.Writer.Header().Get("Allow")
+	respondError(c, 405, "method_not_allowed", fmt.Sprintf("Method %s not allowed; use %s", c.Request.Method, allowed))
+}
+
+// Middleware applied to every request. CORS, logging and stats come before
+// anything that can reject a request, so rejections still carry CORS
+// headers and show up in the logs and stats.
+func globalMiddleware() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		recoveryMiddleware(),
+		requestIDMiddleware(),
+		serverTimeMiddleware(),
+		prettyJSONMiddleware(),
+		corsMiddleware(config.CORSAllowedOrigins, config.CORSMaxAge, config.CORSExposedHeaders),
+		accessLogger(),
+		slowRequestLogger(config.SlowRequestThreshold),
+		statsMiddleware(),
+		allowedHostsMiddleware(config.AllowedHosts),
+		drainingMiddleware(),
+		maintenanceMiddleware(),
+		maxURILengthMiddleware(config.MaxURILength),
+		rejectControlCharsMiddleware(),
+		maxBodyMiddleware(config.MaxBodyBytes),
+		requireJSONContentType(),
+	}
+}
+
 func main() {
+	if err := parseSettingFlags(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	config = loadConfig()
+	registerJSONFieldNames()
+
+	// Fail fast on bad configuration rather than erroring at runtime
+	if problems := validateStartup(config); len(problems) > 0 {
+		log.Fatalf("Invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	// Release mode silences Gin's debug banner and route dump; so does a
+	// LOG_LEVEL above info in debug mode
+	gin.SetMode(config.GinMode)
+	currentLogLevel, _ = parseLogLevel(config.LogLevel)
+	if currentLogLevel > levelInfo {
+		gin.DebugPrintFunc = func(string, ...interface{}) {}
+	}
+
+	// Replace the built-in demo users when a seed file is configured
+	usersFromFile := false
+	if config.DemoUsersFile != "" {
+		users, err := loadDemoUsersFile(config.DemoUsersFile)
+		if err != nil {
+			logEvent(levelWarn, "auth", "demo_users_file_failed", map[string]interface{}{"file": config.DemoUsersFile, "error": err.Error()},
+				fmt.Sprintf("⚠️  Failed to load DEMO_USERS_FILE, using built-in users: %v", err))
+		} else {
+			demoUsers = users
+			usersFromFile = true
+		}
+	}
+	initialDemoUsers = append([]DemoUser(nil), demoUsers...)
+
 	// Initialize Vortex
 	initVortex()
+	readiness.start(config.ReadinessProbeInterval)
+	maintenance.Store(config.MaintenanceMode)
 
-	// Setup Gin router
-	r := gin.Default()
+	// Setup Gin router with our own recovery so panics return the error envelope
+	r := gin.New()
+
+	// API paths are normalized before routing (see normalizeAPITrailingSlash);
+	// static routes keep Gin's redirect to the canonical path
+	r.RedirectTrailingSlash = true
+
+	r.Use(globalMiddleware()...)
+
+	// Only honor X-Forwarded-For from known proxies so c.ClientIP() is reliable
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
+	internalOnly, err := internalOnlyMiddleware(config.InternalOnlyPaths, config.TrustedProxies)
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	r.Use(internalOnly)
 
 	// Serve static files
-	r.Static("/static", "./public")
-	r.StaticFile("/", "./public/index.html")
+	indexFile := filepath.Join(config.StaticDir, "index.html")
+	r.Static("/static", config.StaticDir)
+	r.StaticFile("/", indexFile)
 
 	// Setup routes
 	setupAuthRoutes(r)
 	setupDemoRoutes(r)
 	setupVortexRoutes(r)
 
-	// Health check
+	// Health check, and readiness gated on reaching Vortex
 	r.GET("/health", healthHandler)
+	r.GET("/ready", readyHandler)
+
+	// Client-side routes fall back to the SPA; unknown API paths get JSON
+	r.NoRoute(noRouteHandler(indexFile))
+
+	// Known paths hit with the wrong method get 405; Gin sets the Allow header
+	r.HandleMethodNotAllowed = true
+	r.NoMethod(noMethodHandler)
 
-	// Get port from environment
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
+	port := config.Port
+	useTLS := config.TLSCertFile != ""
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
 	}
 
-	// Convert port to int for validation
-	if _, err := strconv.Atoi(port); err != nil {
-		log.Fatal("Invalid PORT environment variable")
+	logEvent(levelInfo, "server", "starting", map[string]interface{}{
+		"port":      port,
+		"scheme":    scheme,
+		"apiPrefix": config.APIPrefix,
+		"version":   version,
+	}, fmt.Sprintf("🚀 Demo Go server starting on port %s", port))
+	logTextf("📱 Visit %s://localhost:%s to try the demo", scheme, port)
+	logTextf("🔧 Vortex API routes available at %s://localhost:%s%s", scheme, port, apiPath("/api/vortex"))
+	logTextf("📊 Health check: %s://localhost:%s/health", scheme, port)
+	logTextf("")
+	if usersFromFile {
+		logEvent(levelInfo, "auth", "demo_users_loaded", map[string]interface{}{"count": len(demoUsers), "file": config.DemoUsersFile},
+			fmt.Sprintf("Loaded %d demo users from %s", len(demoUsers), config.DemoUsersFile))
+	} else {
+		logEvent(levelInfo, "auth", "demo_users_loaded", map[string]interface{}{"count": len(demoUsers), "source": "built-in"}, "Demo users:")
+		logTextf("  - admin@example.com / password123 (admin role)")
+		logTextf("  - user@example.com / userpass (user role)")
 	}
 
-	log.Printf("🚀 Demo Go server starting on port %s", port)
-	log.Printf("📱 Visit http://localhost:%s to try the demo", port)
-	log.Printf("🔧 Vortex API routes available at http://localhost:%s/api/vortex", port)
-	log.Printf("📊 Health check: http://localhost:%s/health", port)
-	log.Println()
-	log.Println("Demo users:")
-	log.Println("  - admin@example.com / password123 (admin role)")
-	log.Println("  - user@example.com / userpass (user role)")
+	// Start server; the TLS listener negotiates HTTP/2 automatically
+	srv := &http.Server{Addr: ":" + port, Handler: normalizeAPITrailingSlash(r)}
+	go func() {
+		var err error
+		if useTLS {
+			err = srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
 
-	// Start server
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	// On SIGINT/SIGTERM, reject new requests and give in-flight ones (and
+	// their Vortex calls) up to SHUTDOWN_TIMEOUT to finish
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	<-stop.Done()
+
+	logEvent(levelInfo, "server", "shutting_down", map[string]interface{}{"timeout": config.ShutdownTimeout.String()},
+		fmt.Sprintf("🛑 Shutting down, draining in-flight requests (up to %s)", config.ShutdownTimeout))
+	draining.Store(true)
+	ctx, cancelDrain := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancelDrain()
+	if err := srv.Shutdown(ctx); err != nil {
+		logEvent(levelWarn, "server", "shutdown_incomplete", map[string]interface{}{"error": err.Error()},
+			fmt.Sprintf("⚠️  Shutdown did not finish cleanly: %v", err))
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,665 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+func TestGetInvitationRevalidatesWithETag(t *testing.T) {
+	useInvitations(t, &fakeInvitations{})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("GET", "/api/vortex/invitations/inv-1", nil), demoUsers[0])
+	first := serve(router, req)
+	etag := first.Header().Get("ETag")
+	if first.Code != 200 || etag == "" {
+		t.Fatalf("status = %d, ETag = %q, want 200 with an ETag", first.Code, etag)
+	}
+
+	req = withSession(t, httptest.NewRequest("GET", "/api/vortex/invitations/inv-1", nil), demoUsers[0])
+	req.Header.Set("If-None-Match", etag)
+	second := serve(router, req)
+	if second.Code != 304 || second.Body.Len() != 0 {
+		t.Fatalf("revalidation status = %d, body = %q, want empty 304", second.Code, second.Body.String())
+	}
+
+	req = withSession(t, httptest.NewRequest("GET", "/api/vortex/invitations/inv-1", nil), demoUsers[0])
+	req.Header.Set("If-None-Match", `W/"stale"`)
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Fatalf("stale ETag status = %d, want 200", rec.Code)
+	}
+}
+
+func TestReinviteAllSkipsAcceptedInvitations(t *testing.T) {
+	useConfig(t, nil)
+	var reinvited atomic.Int32
+	useInvitations(t, &fakeInvitations{
+		byGroup: func(groupType, groupID string) ([]vortex.InvitationResult, error) {
+			return []vortex.InvitationResult{
+				{ID: "inv-1", Status: "pending"},
+				{ID: "inv-2", Status: "accepted"},
+				{ID: "inv-3", Status: "pending"},
+			}, nil
+		},
+		reinvite: func(id string) (*vortex.InvitationResult, error) {
+			reinvited.Add(1)
+			if id == "inv-3" {
+				return nil, errors.New("delivery failed")
+			}
+			return &vortex.InvitationResult{ID: id}, nil
+		},
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/by-group/team/team-1/reinvite-all", nil), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 207 {
+		t.Fatalf("status = %d, want 207: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Results map[string]struct {
+			Status string `json:"status"`
+		} `json:"results"`
+		Summary map[string]int `json:"summary"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if n := reinvited.Load(); n != 2 {
+		t.Fatalf("reinvited %d invitations, want only the two pending ones", n)
+	}
+	want := map[string]string{"inv-1": "ok", "inv-2": "skipped", "inv-3": "failed"}
+	for id, status := range want {
+		if body.Results[id].Status != status {
+			t.Errorf("results[%s] = %q, want %q", id, body.Results[id].Status, status)
+		}
+	}
+	if body.Summary["total"] != 3 || body.Summary["succeeded"] != 1 || body.Summary["failed"] != 1 || body.Summary["skipped"] != 1 {
+		t.Fatalf("summary = %v", body.Summary)
+	}
+}
+
+func TestEchoReturnsBodyAndStripsSensitiveHeaders(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupDemoRoutes(router)
+
+	req := httptest.NewRequest("POST", "/api/demo/echo", strings.NewReader(`{"hello":"world"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Trace", "abc")
+	rec := serve(router, req)
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Body    map[string]string `json:"body"`
+		Headers map[string]string `json:"headers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Body["hello"] != "world" || body.Headers["X-Trace"] != "abc" {
+		t.Fatalf("echo = %+v", body)
+	}
+	if _, ok := body.Headers["Authorization"]; ok {
+		t.Fatal("Authorization header was echoed")
+	}
+}
+
+func TestEchoRejectsOversizedBody(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupDemoRoutes(router)
+
+	payload := `"` + strings.Repeat("a", maxEchoBodyBytes) + `"`
+	rec := serve(router, httptest.NewRequest("POST", "/api/demo/echo", strings.NewReader(payload)))
+	if rec.Code != 413 || decodeErrorBody(t, rec).Code != "payload_too_large" {
+		t.Fatalf("status = %d, body = %s, want 413 payload_too_large", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNoRouteServesSPAOrJSON404(t *testing.T) {
+	indexFile := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(indexFile, []byte("<html>spa</html>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.NoRoute(noRouteHandler(indexFile))
+
+	rec := serve(router, httptest.NewRequest("GET", "/dashboard/settings", nil))
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), "spa") {
+		t.Fatalf("client route: status = %d, body = %q, want the SPA index", rec.Code, rec.Body.String())
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/api/nope", nil),
+		httptest.NewRequest("POST", "/dashboard", nil),
+	} {
+		rec := serve(router, req)
+		if rec.Code != 404 || decodeErrorBody(t, rec).Code != "not_found" {
+			t.Errorf("%s %s: status = %d, body = %s, want JSON 404", req.Method, req.URL.Path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestJWTTimeClaimsReadsExpiryWithoutVerifying(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": 1700003600,
+		"iat": 1700000000,
+	}).SignedString([]byte("some-other-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwtTimeClaims(token)
+	if claims["exp"] != 1700003600 || claims["iat"] != 1700000000 {
+		t.Fatalf("claims = %v", claims)
+	}
+	if claims := jwtTimeClaims("not-a-jwt"); len(claims) != 0 {
+		t.Fatalf("claims for garbage = %v, want none", claims)
+	}
+}
+
+func TestDisabledFeatureRoutesReturn404(t *testing.T) {
+	useConfig(t, func(cfg *Config) {
+		cfg.Features.Echo = false
+		cfg.DemoBanner = "Public demo"
+	})
+	router := gin.New()
+	setupDemoRoutes(router)
+
+	rec := serve(router, httptest.NewRequest("POST", "/api/demo/echo", strings.NewReader(`{}`)))
+	if rec.Code != 404 || decodeErrorBody(t, rec).Code != "not_found" {
+		t.Fatalf("disabled echo: status = %d, body = %s, want 404 not_found", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Features Features `json:"features"`
+		Banner   string   `json:"banner"`
+	}
+	rec = serve(router, httptest.NewRequest("GET", "/api/demo/features", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if body.Features.Echo || !body.Features.Stats || !body.Features.Impersonation || body.Banner != "Public demo" {
+		t.Fatalf("features = %+v", body)
+	}
+}
+
+func TestDecodeJWTIsAdminOnlyAndUnverified(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-9"}).SignedString([]byte("anything"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/vortex/jwt/decode?token=" + token
+
+	rec := serve(router, withSession(t, httptest.NewRequest("GET", path, nil), demoUsers[1]))
+	if rec.Code != 403 {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+
+	rec = serve(router, withSession(t, httptest.NewRequest("GET", path, nil), demoUsers[0]))
+	var body struct {
+		Verified bool           `json:"verified"`
+		Header   map[string]any `json:"header"`
+		Claims   map[string]any `json:"claims"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != 200 {
+		t.Fatalf("admin: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if body.Verified || body.Claims["sub"] != "user-9" || body.Header["alg"] != "HS256" {
+		t.Errorf("decoded = %+v", body)
+	}
+
+	rec = serve(router, withSession(t, httptest.NewRequest("GET", "/api/vortex/jwt/decode?token=nope", nil), demoUsers[0]))
+	if rec.Code != 400 || decodeErrorBody(t, rec).Code != "invalid_token" {
+		t.Errorf("garbage token: status = %d, body = %s, want 400 invalid_token", rec.Code, rec.Body)
+	}
+}
+
+func TestWhoamiScopesMatchesVortexScopes(t *testing.T) {
+	router := gin.New()
+	setupDemoRoutes(router)
+
+	tests := []struct {
+		user DemoUser
+		want string
+	}{
+		{demoUsers[0], `{"scopes":["autojoin"]}`},
+		{demoUsers[1], `{"scopes":[]}`},
+	}
+	for _, tt := range tests {
+		rec := serve(router, withSession(t, httptest.NewRequest("GET", "/api/demo/whoami-scopes", nil), tt.user))
+		if rec.Code != 200 || rec.Body.String() != tt.want {
+			t.Errorf("%s: status = %d, body = %s, want %s", tt.user.Email, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestAcceptGroupAcceptsPendingInvitations(t *testing.T) {
+	var accepted []string
+	var target vortex.InvitationTarget
+	useInvitations(t, &fakeInvitations{
+		byGroup: func(groupType, groupID string) ([]vortex.InvitationResult, error) {
+			return []vortex.InvitationResult{
+				{ID: "inv-1", Status: "pending"},
+				{ID: "inv-2", Status: "accepted"},
+				{ID: "inv-3", Status: "pending"},
+			}, nil
+		},
+		accept: func(ids []string, t vortex.InvitationTarget) (*vortex.InvitationResult, error) {
+			accepted, target = ids, t
+			return &vortex.InvitationResult{ID: ids[0], Status: "accepted"}, nil
+		},
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/by-group/team/team-1/accept", nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if strings.Join(accepted, ",") != "inv-1,inv-3" {
+		t.Errorf("accepted %v, want the two pending invitations", accepted)
+	}
+	if target.Type != "team" || target.Value != "team-1" {
+		t.Errorf("target = %+v, want team/team-1", target)
+	}
+}
+
+func TestAcceptGroupWithNothingPendingIs404(t *testing.T) {
+	useInvitations(t, &fakeInvitations{})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/by-group/team/team-1/accept", nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 404 || decodeErrorBody(t, rec).Code != "not_found" {
+		t.Errorf("status = %d, body = %s, want 404 not_found", rec.Code, rec.Body)
+	}
+}
+
+func TestNoRouteJSON404FollowsAPIPrefix(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.APIPrefix = "/svc" })
+	indexFile := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(indexFile, []byte("<html>spa</html>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	setupAuthRoutes(router)
+	router.NoRoute(noRouteHandler(indexFile))
+
+	for _, path := range []string{"/svc/api", "/svc/api/auth/nope"} {
+		rec := serve(router, httptest.NewRequest("GET", path, nil))
+		if rec.Code != 404 || decodeErrorBody(t, rec).Code != "not_found" {
+			t.Errorf("%s: status = %d, body = %s, want JSON 404", path, rec.Code, rec.Body)
+		}
+	}
+	if rec := serve(router, httptest.NewRequest("GET", "/api/auth/nope", nil)); !strings.Contains(rec.Body.String(), "spa") {
+		t.Errorf("unprefixed path: body = %q, want the SPA index", rec.Body)
+	}
+}
+
+func TestWrongMethodIsJSON405WithAllow(t *testing.T) {
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.NoMethod(noMethodHandler)
+	setupAuthRoutes(router)
+
+	rec := serve(router, httptest.NewRequest("DELETE", "/api/auth/login", nil))
+	if rec.Code != 405 || decodeErrorBody(t, rec).Code != "method_not_allowed" {
+		t.Fatalf("status = %d, body = %s, want 405 method_not_allowed", rec.Code, rec.Body)
+	}
+	if allow := rec.Header().Get("Allow"); !strings.Contains(allow, "POST") {
+		t.Errorf("Allow = %q, want POST", allow)
+	}
+}
+
+func TestDemoResetRestoresStartupState(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.Features.DemoReset = true })
+	useDemoUsers(t, demoUsers)
+	savedInitial := initialDemoUsers
+	t.Cleanup(func() { initialDemoUsers = savedInitial })
+	initialDemoUsers = append([]DemoUser(nil), demoUsers...)
+
+	token, _ := createSessionJWT(demoUsers[1])
+	user, _ := verifySessionJWT(token)
+	sessions.revoke(user.SessionID)
+	demoUsers = demoUsers[:1]
+
+	router := gin.New()
+	setupDemoRoutes(router)
+	if rec := serve(router, httptest.NewRequest("POST", "/api/demo/reset", nil)); rec.Code != 200 {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if len(demoUsers) != len(initialDemoUsers) {
+		t.Errorf("%d demo users after reset, want %d", len(demoUsers), len(initialDemoUsers))
+	}
+	if sessions.isRevoked(user.SessionID) {
+		t.Error("revocation survived reset")
+	}
+}
+
+func TestDemoResetIsOffByDefault(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupDemoRoutes(router)
+	if rec := serve(router, httptest.NewRequest("POST", "/api/demo/reset", nil)); rec.Code != 404 {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestReinviteCooldownThrottlesRepeats(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.ReinviteCooldown = time.Minute })
+	t.Cleanup(reinviteCooldowns.reset)
+	fail := false
+	useInvitations(t, &fakeInvitations{reinvite: func(id string) (*vortex.InvitationResult, error) {
+		if fail {
+			return nil, errors.New("delivery failed")
+		}
+		return &vortex.InvitationResult{ID: id}, nil
+	}})
+	router := gin.New()
+	setupVortexRoutes(router)
+	reinvite := func(id string) *httptest.ResponseRecorder {
+		return serve(router, withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/"+id+"/reinvite", nil), demoUsers[0]))
+	}
+
+	if rec := reinvite("cool-1"); rec.Code != 200 {
+		t.Fatalf("first reinvite: status = %d", rec.Code)
+	}
+	rec := reinvite("cool-1")
+	if rec.Code != 429 || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("repeat: status = %d, Retry-After = %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	fail = true
+	reinvite("cool-2")
+	fail = false
+	if rec := reinvite("cool-2"); rec.Code != 200 {
+		t.Errorf("retry after a failed reinvite: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestDemoResetClearsReinviteCooldowns(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.Features.DemoReset = true })
+	useDemoUsers(t, demoUsers)
+	savedInitial := initialDemoUsers
+	t.Cleanup(func() { initialDemoUsers = savedInitial })
+	initialDemoUsers = demoUsers
+
+	reinviteCooldowns.claim("reset-1", time.Minute)
+	router := gin.New()
+	setupDemoRoutes(router)
+	serve(router, httptest.NewRequest("POST", "/api/demo/reset", nil))
+
+	if _, ok := reinviteCooldowns.claim("reset-1", time.Minute); !ok {
+		t.Error("cooldown survived reset")
+	}
+	reinviteCooldowns.reset()
+}
+
+func TestAcceptRejectsUnknownTargetTypeWithAllowed(t *testing.T) {
+	called := false
+	useInvitations(t, &fakeInvitations{accept: func([]string, vortex.InvitationTarget) (*vortex.InvitationResult, error) {
+		called = true
+		return &vortex.InvitationResult{}, nil
+	}})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	payload := `{"invitationIds":["inv-1"],"target":{"type":"fax","value":"555-0100"}}`
+	rec := serve(router, withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/accept", strings.NewReader(payload)), demoUsers[0]))
+	body := decodeErrorBody(t, rec)
+	if rec.Code != 400 || body.Code != "invalid_target_type" {
+		t.Fatalf("status = %d, body = %s, want 400 invalid_target_type", rec.Code, rec.Body)
+	}
+	if strings.Join(body.Allowed, ",") != strings.Join(allowedTargetTypes, ",") {
+		t.Errorf("allowed = %v, want %v", body.Allowed, allowedTargetTypes)
+	}
+	if called {
+		t.Error("SDK called with an unknown target type")
+	}
+}
+
+func TestProtectedAdminRequiresAutojoinAdmin(t *testing.T) {
+	router := gin.New()
+	setupDemoRoutes(router)
+
+	tests := []struct {
+		user DemoUser
+		want int
+	}{
+		{demoUsers[0], 200},
+		{demoUsers[1], 403},
+	}
+	for _, tt := range tests {
+		rec := serve(router, withSession(t, httptest.NewRequest("GET", "/api/demo/protected-admin", nil), tt.user))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.user.Email, rec.Code, tt.want)
+		}
+	}
+	if rec := serve(router, httptest.NewRequest("GET", "/api/demo/protected-admin", nil)); rec.Code != 401 {
+		t.Errorf("anonymous: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestDeleteUserSoftDeletesAndRevokesSessions(t *testing.T) {
+	useConfig(t, nil)
+	useDemoUsers(t, append([]DemoUser(nil), demoUsers...))
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	token, _ := createSessionJWT(demoUsers[1])
+	req := withSession(t, httptest.NewRequest("DELETE", "/api/auth/users/"+demoUsers[1].ID, nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Error("deleted user's session still verifies")
+	}
+	if user := authenticateUser(demoUsers[1].Email, "userpass"); user != nil {
+		t.Error("deleted user can still log in")
+	}
+	for _, user := range getDemoUsers() {
+		if user.ID == demoUsers[1].ID {
+			t.Error("deleted user still listed")
+		}
+	}
+
+	req = withSession(t, httptest.NewRequest("DELETE", "/api/auth/users/"+demoUsers[1].ID, nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 404 {
+		t.Errorf("deleting twice: status = %d, want 404", rec.Code)
+	}
+	req = withSession(t, httptest.NewRequest("DELETE", "/api/auth/users/"+demoUsers[0].ID, nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 400 {
+		t.Errorf("deleting self: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestBatchJWTReportsErrorsPerUser(t *testing.T) {
+	useConfig(t, nil)
+	saved := vortexClient
+	t.Cleanup(func() { vortexClient = saved })
+	vortexClient = vortex.NewClient("test-api-key")
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	body := `{"users":[{"email":"a@example.com"},{"id":"u-2"},{"id":"u-3","email":"c@example.com"}]}`
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/jwt/batch", strings.NewReader(body)), demoUsers[1])
+	if rec := serve(router, req); rec.Code != 403 {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+
+	req = withSession(t, httptest.NewRequest("POST", "/api/vortex/jwt/batch", strings.NewReader(body)), demoUsers[0])
+	rec := serve(router, req)
+	var resp struct {
+		Results map[string]BatchItemResult `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != 207 {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	want := map[string]string{"#0": batchStatusFailed, "u-2": batchStatusFailed, "u-3": batchStatusOK}
+	for key, status := range want {
+		if resp.Results[key].Status != status {
+			t.Errorf("results[%s] = %+v, want %s", key, resp.Results[key], status)
+		}
+	}
+	if len(resp.Results) != len(want) {
+		t.Errorf("results = %v, want %d entries", resp.Results, len(want))
+	}
+
+	users := strings.Repeat(`{"id":"u","email":"u@example.com"},`, maxBatchJWTUsers+1)
+	tooMany := `{"users":[` + strings.TrimSuffix(users, ",") + `]}`
+	req = withSession(t, httptest.NewRequest("POST", "/api/vortex/jwt/batch", strings.NewReader(tooMany)), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 400 {
+		t.Errorf("too many users: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestInvitationListCacheControl(t *testing.T) {
+	path := "/api/vortex/invitations?targetType=email&targetValue=a@example.com"
+
+	useConfig(t, nil)
+	rec, _ := listInvitationIDs(t, path, nil)
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("default Cache-Control = %q, want no-store", got)
+	}
+
+	config.InvitationListCacheControl = "private, max-age=10"
+	rec, _ = listInvitationIDs(t, path, nil)
+	if got := rec.Header().Get("Cache-Control"); got != "private, max-age=10" {
+		t.Errorf("configured Cache-Control = %q", got)
+	}
+}
+
+func TestSimulateJWTErrorIsGatedAndFails(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("GET", "/api/vortex/jwt/simulate-error", nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 404 {
+		t.Errorf("feature off: status = %d, want 404", rec.Code)
+	}
+
+	config.Features.SimulatedErrors = true
+	req = withSession(t, httptest.NewRequest("GET", "/api/vortex/jwt/simulate-error", nil), demoUsers[1])
+	if rec := serve(router, req); rec.Code != 403 {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+	req = withSession(t, httptest.NewRequest("GET", "/api/vortex/jwt/simulate-error", nil), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 500 || decodeErrorBody(t, rec).Code != "jwt_failed" {
+		t.Errorf("admin: status = %d, body = %s, want 500 jwt_failed", rec.Code, rec.Body)
+	}
+}
+
+func TestExportGroupInvitationsStreamsNDJSON(t *testing.T) {
+	useConfig(t, nil)
+	useInvitations(t, &fakeInvitations{byGroup: func(groupType, groupID string) ([]vortex.InvitationResult, error) {
+		return []vortex.InvitationResult{{ID: "exp-1"}, {ID: "exp-2"}}, nil
+	}})
+	router := gin.New()
+	setupVortexRoutes(router)
+	path := "/api/vortex/invitations/by-group/team/t1/export"
+
+	if rec := serve(router, withSession(t, httptest.NewRequest("GET", path, nil), demoUsers[1])); rec.Code != 403 {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+
+	rec := serve(router, withSession(t, httptest.NewRequest("GET", path, nil), demoUsers[0]))
+	if rec.Code != 200 || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want 2", lines)
+	}
+	var first vortex.InvitationResult
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.ID != "exp-1" {
+		t.Errorf("first line = %s, err = %v", lines[0], err)
+	}
+}
+
+func TestMaintenanceToggleIsAdminOnly(t *testing.T) {
+	useConfig(t, nil)
+	t.Cleanup(func() { maintenance.Store(false) })
+	router := gin.New()
+	setupDemoRoutes(router)
+	toggle := func(user DemoUser, body string) int {
+		req := httptest.NewRequest("POST", "/api/demo/maintenance", strings.NewReader(body))
+		return serve(router, withSession(t, req, user)).Code
+	}
+
+	if got := toggle(demoUsers[1], ""); got != 403 || maintenance.Load() {
+		t.Errorf("non-admin: status = %d, maintenance = %v, want 403 and off", got, maintenance.Load())
+	}
+	if got := toggle(demoUsers[0], ""); got != 200 || !maintenance.Load() {
+		t.Errorf("empty body: status = %d, maintenance = %v, want 200 and on", got, maintenance.Load())
+	}
+	if got := toggle(demoUsers[0], `{"enabled": false}`); got != 200 || maintenance.Load() {
+		t.Errorf("enabled false: status = %d, maintenance = %v, want 200 and off", got, maintenance.Load())
+	}
+}
+
+func TestAcceptConflictIsAlreadyAccepted(t *testing.T) {
+	useConfig(t, nil)
+	useInvitations(t, &fakeInvitations{accept: func([]string, vortex.InvitationTarget) (*vortex.InvitationResult, error) {
+		return nil, &vortex.APIError{StatusCode: 409, Message: "invitation already accepted"}
+	}})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	payload := `{"invitationIds":["inv-1"],"target":{"type":"email","value":"a@example.com"}}`
+	rec := serve(router, withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/accept", strings.NewReader(payload)), demoUsers[0]))
+	if rec.Code != 409 || decodeErrorBody(t, rec).Code != "already_accepted" {
+		t.Errorf("status = %d, body = %s, want 409 already_accepted", rec.Code, rec.Body)
+	}
+}
+
+func TestTargetTypesListsAllowedTypesWithDescriptions(t *testing.T) {
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	rec := serve(router, withSession(t, httptest.NewRequest("GET", "/api/vortex/target-types", nil), demoUsers[1]))
+	var body struct {
+		TargetTypes []struct {
+			Type        string `json:"type"`
+			Description string `json:"description"`
+		} `json:"targetTypes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); rec.Code != 200 || err != nil {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	var types []string
+	for _, tt := range body.TargetTypes {
+		types = append(types, tt.Type)
+		if tt.Description == "" {
+			t.Errorf("%s has no description", tt.Type)
+		}
+	}
+	if strings.Join(types, ",") != "email,username,phoneNumber" {
+		t.Errorf("types = %v, want email,username,phoneNumber", types)
+	}
+}
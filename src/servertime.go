@@ -0,0 +1,25 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response header carrying the server's clock when it took the request
+const serverTimeHeader = "X-Server-Time"
+
+// Format a time the way every response does: RFC3339 in UTC
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Stamp every response with the server's current time so clients can detect
+// clock skew. A header rather than a body field keeps Vortex resources
+// passed through unchanged and covers non-JSON responses too.
+func serverTimeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(serverTimeHeader, formatTimestamp(time.Now()))
+		c.Next()
+	}
+}
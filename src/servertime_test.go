@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResponsesCarryRFC3339ServerTime(t *testing.T) {
+	useConfig(t, nil)
+	saved := gin.DefaultWriter
+	t.Cleanup(func() { gin.DefaultWriter = saved })
+	gin.DefaultWriter = io.Discard
+
+	router := gin.New()
+	router.Use(globalMiddleware()...)
+	router.GET("/health", healthHandler)
+	setupDemoRoutes(router)
+
+	requests := map[string]*httptest.ResponseRecorder{
+		"/health":             serve(router, httptest.NewRequest("GET", "/health", nil)),
+		"/api/demo/protected": serve(router, withSession(t, httptest.NewRequest("GET", "/api/demo/protected", nil), demoUsers[0])),
+	}
+	for path, rec := range requests {
+		if rec.Code != 200 {
+			t.Fatalf("%s: status = %d", path, rec.Code)
+		}
+
+		stamp := rec.Header().Get(serverTimeHeader)
+		if parsed, err := time.Parse(time.RFC3339, stamp); err != nil || !strings.HasSuffix(stamp, "Z") || time.Since(parsed) > time.Minute {
+			t.Errorf("%s: %s = %q, want the current time as RFC3339 UTC", path, serverTimeHeader, stamp)
+		}
+
+		var body struct {
+			Timestamp string `json:"timestamp"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if _, err := time.Parse(time.RFC3339, body.Timestamp); err != nil || !strings.HasSuffix(body.Timestamp, "Z") {
+			t.Errorf("%s: body timestamp = %q, want RFC3339 UTC", path, body.Timestamp)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// sessionRecord tracks an issued session token by its jti
+type sessionRecord struct {
+	JTI       string
+	UserID    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// sessionStore keeps issued and revoked session IDs in memory
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]sessionRecord
+	revoked  map[string]time.Time // jti -> token expiry
+}
+
+var sessions = newSessionStore()
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		sessions: make(map[string]sessionRecord),
+		revoked:  make(map[string]time.Time),
+	}
+}
+
+// Record a newly issued session
+func (s *sessionStore) track(record sessionRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	s.sessions[record.JTI] = record
+}
+
+// Revoke a single session; its token stops verifying immediately
+func (s *sessionStore) revoke(jti string) {
+	if jti == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if record, ok := s.sessions[jti]; ok {
+		expiresAt = record.ExpiresAt
+		delete(s.sessions, jti)
+	}
+	s.revoked[jti] = expiresAt
+}
+
+// Revoke every outstanding session for a user, returning how many were revoked
+func (s *sessionStore) revokeAll(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for jti, record := range s.sessions {
+		if record.UserID == userID {
+			s.revoked[jti] = record.ExpiresAt
+			delete(s.sessions, jti)
+			count++
+		}
+	}
+	return count
+}
+
+// Active sessions for a user, newest first
+func (s *sessionStore) listForUser(userID string) []sessionRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	var records []sessionRecord
+	for _, record := range s.sessions {
+		if record.UserID == userID {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].IssuedAt.After(records[j].IssuedAt)
+	})
+	return records
+}
+
+// Revoke one of a user's sessions. Returns false if the user has no such
+// active session, so users can't revoke each other's tokens.
+func (s *sessionStore) revokeForUser(userID, jti string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.sessions[jti]
+	if !ok || record.UserID != userID {
+		return false
+	}
+	s.revoked[jti] = record.ExpiresAt
+	delete(s.sessions, jti)
+	return true
+}
+
+// Check whether a session has been revoked
+func (s *sessionStore) isRevoked(jti string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, revoked := s.revoked[jti]
+	return revoked
+}
+
+// Forget all tracked and revoked sessions
+func (s *sessionStore) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions = make(map[string]sessionRecord)
+	s.revoked = make(map[string]time.Time)
+}
+
+// Drop records for tokens that have expired anyway
+func (s *sessionStore) pruneLocked(now time.Time) {
+	for jti, record := range s.sessions {
+		if now.After(record.ExpiresAt) {
+			delete(s.sessions, jti)
+		}
+	}
+	for jti, expiresAt := range s.revoked {
+		if now.After(expiresAt) {
+			delete(s.revoked, jti)
+		}
+	}
+}
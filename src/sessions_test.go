@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRevokedSessionIsRejected(t *testing.T) {
+	useConfig(t, nil)
+	token, err := createSessionJWT(demoUsers[1])
+	if err != nil {
+		t.Fatalf("createSessionJWT: %v", err)
+	}
+	user, err := verifySessionJWT(token)
+	if err != nil {
+		t.Fatalf("fresh token rejected: %v", err)
+	}
+
+	sessions.revoke(user.SessionID)
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Fatal("revoked token still verifies")
+	}
+}
+
+func TestLogoutAllRevokesEverySessionForUser(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	first, _ := createSessionJWT(demoUsers[0])
+	second, _ := createSessionJWT(demoUsers[0])
+	other, _ := createSessionJWT(demoUsers[1])
+
+	req := withSession(t, httptest.NewRequest("POST", "/api/auth/logout-all", nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, token := range []string{first, second} {
+		if _, err := verifySessionJWT(token); err == nil {
+			t.Fatal("session survived logout-all")
+		}
+	}
+	if _, err := verifySessionJWT(other); err != nil {
+		t.Fatalf("another user's session was revoked: %v", err)
+	}
+}
+
+func TestUsersCanOnlyRevokeTheirOwnSessions(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupAuthRoutes(router)
+
+	token, _ := createSessionJWT(demoUsers[0])
+	own, err := verifySessionJWT(token)
+	if err != nil {
+		t.Fatalf("fresh token rejected: %v", err)
+	}
+	otherToken, _ := createSessionJWT(demoUsers[1])
+	other, _ := verifySessionJWT(otherToken)
+
+	found := false
+	for _, record := range sessions.listForUser(demoUsers[0].ID) {
+		found = found || record.JTI == own.SessionID
+		if record.UserID != demoUsers[0].ID {
+			t.Errorf("listed another user's session %s", record.JTI)
+		}
+	}
+	if !found {
+		t.Error("new session missing from listForUser")
+	}
+
+	req := withSession(t, httptest.NewRequest("DELETE", "/api/auth/sessions/"+other.SessionID, nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 404 {
+		t.Errorf("revoking another user's session: status = %d, want 404", rec.Code)
+	}
+	req = withSession(t, httptest.NewRequest("DELETE", "/api/auth/sessions/"+own.SessionID, nil), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Errorf("revoking own session: status = %d, want 200", rec.Code)
+	}
+	if _, err := verifySessionJWT(token); err == nil {
+		t.Error("revoked session still verifies")
+	}
+	if _, err := verifySessionJWT(otherToken); err != nil {
+		t.Errorf("other user's session was revoked: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeStats accumulates request metrics for a single route
+type routeStats struct {
+	Count        int64
+	Errors       int64
+	TotalLatency time.Duration
+}
+
+// RouteStatsView is the JSON shape of a route's stats
+type RouteStatsView struct {
+	Route        string  `json:"route"`
+	Count        int64   `json:"count"`
+	Errors       int64   `json:"errors"`
+	ErrorRate    float64 `json:"errorRate"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+}
+
+// statsCollector holds per-route request stats in memory
+type statsCollector struct {
+	mu     sync.Mutex
+	routes map[string]*routeStats
+	since  time.Time
+}
+
+var requestStats = newStatsCollector()
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{routes: make(map[string]*routeStats), since: time.Now()}
+}
+
+// Record a completed request; 4xx and 5xx responses count as errors
+func (s *statsCollector) record(route string, status int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.routes[route]
+	if !ok {
+		stats = &routeStats{}
+		s.routes[route] = stats
+	}
+	stats.Count++
+	stats.TotalLatency += latency
+	if status >= 400 {
+		stats.Errors++
+	}
+}
+
+// Snapshot the collected stats, sorted by route
+func (s *statsCollector) snapshot() []RouteStatsView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	views := make([]RouteStatsView, 0, len(s.routes))
+	for route, stats := range s.routes {
+		views = append(views, RouteStatsView{
+			Route:        route,
+			Count:        stats.Count,
+			Errors:       stats.Errors,
+			ErrorRate:    float64(stats.Errors) / float64(stats.Count),
+			AvgLatencyMs: float64(stats.TotalLatency.Microseconds()) / float64(stats.Count) / 1000,
+		})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Route < views[j].Route })
+	return views
+}
+
+// Middleware recording per-route request counts, latency and errors
+func statsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// Key by route pattern so path params don't explode the map
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requestStats.record(c.Request.Method+" "+route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+func getStatsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"since":  formatTimestamp(requestStats.since),
+		"routes": requestStats.snapshot(),
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStatsRecordsCountsAndErrorsPerRoute(t *testing.T) {
+	useConfig(t, nil)
+	saved := requestStats
+	t.Cleanup(func() { requestStats = saved })
+	requestStats = newStatsCollector()
+
+	router := gin.New()
+	router.Use(statsMiddleware())
+	setupDemoRoutes(router)
+	router.GET("/items/:id", func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.Status(404)
+			return
+		}
+		c.Status(200)
+	})
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/missing"} {
+		serve(router, httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := serve(router, withSession(t, httptest.NewRequest("GET", "/api/demo/stats", nil), demoUsers[0]))
+	var body struct {
+		Routes []RouteStatsView `json:"routes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+
+	var items *RouteStatsView
+	for i := range body.Routes {
+		if body.Routes[i].Route == "GET /items/:id" {
+			items = &body.Routes[i]
+		}
+	}
+	if items == nil {
+		t.Fatalf("routes = %+v, want GET /items/:id keyed by pattern", body.Routes)
+	}
+	if items.Count != 3 || items.Errors != 1 || items.ErrorRate < 0.33 || items.ErrorRate > 0.34 {
+		t.Fatalf("stats = %+v, want 3 requests with 1 error", *items)
+	}
+}
+
+func TestStatsRequiresAdmin(t *testing.T) {
+	useConfig(t, nil)
+	router := gin.New()
+	setupDemoRoutes(router)
+
+	rec := serve(router, withSession(t, httptest.NewRequest("GET", "/api/demo/stats", nil), demoUsers[1]))
+	if rec.Code != 403 {
+		t.Fatalf("status = %d, want 403", rec.Code)
+	}
+}
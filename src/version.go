@@ -0,0 +1,25 @@
+package main
+
+import "time"
+
+// Build metadata, set at build time with e.g.
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./src
+var (
+	version   = "dev"
+	commit    = "dev"
+	buildDate = "dev"
+)
+
+// When the process started, for uptime reporting
+var startTime = time.Now()
+
+// Build metadata and uptime as reported by /health
+func buildInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"version":       version,
+		"commit":        commit,
+		"buildDate":     buildDate,
+		"uptimeSeconds": int64(time.Since(startTime).Seconds()),
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildInfoReportsMetadataAndUptime(t *testing.T) {
+	saved := startTime
+	t.Cleanup(func() { startTime = saved })
+	startTime = time.Now().Add(-90 * time.Second)
+
+	info := buildInfo()
+	if info["version"] != version || info["commit"] != commit || info["buildDate"] != buildDate {
+		t.Errorf("build metadata = %v", info)
+	}
+	if uptime := info["uptimeSeconds"].(int64); uptime < 90 || uptime > 91 {
+		t.Errorf("uptimeSeconds = %d, want 90", uptime)
+	}
+}
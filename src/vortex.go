@@ -0,0 +1,500 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+	"golang.org/x/sync/singleflight"
+)
+
+const defaultVortexAPIBaseURL = "https://api.vortexsoftware.com"
+
+// Target types accepted by the invitation endpoints
+var allowedTargetTypes = []string{"email", "username", "phoneNumber"}
+
+// Short descriptions of each allowed target type, for GET /target-types
+var targetTypeDescriptions = map[string]string{
+	"email":       "An email address; matched case-insensitively",
+	"username":    "A username in your application",
+	"phoneNumber": "A phone number, ideally in E.164 format (e.g. +15551234567)",
+}
+
+// Direct HTTP access for Vortex endpoints the SDK does not wrap
+var (
+	vortexAPIKey     string
+	vortexHTTPClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// Build an HTTP client tuned for Vortex API calls. The SDK client manages
+// its own transport, so this applies to the calls made from this package.
+func newVortexHTTPClient(cfg Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.VortexMaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.VortexMaxIdleConns
+	transport.IdleConnTimeout = cfg.VortexIdleConnTimeout
+
+	return &http.Client{
+		Timeout:   cfg.VortexHTTPTimeout,
+		Transport: transport,
+	}
+}
+
+// How long a call waits for a free slot before giving up
+const vortexSlotWait = 2 * time.Second
+
+var errVortexBusy = errors.New("too many concurrent Vortex requests")
+
+// Semaphore bounding in-flight Vortex calls, sized by VORTEX_MAX_CONCURRENCY
+var vortexSlots chan struct{}
+
+// Take a Vortex call slot, waiting up to vortexSlotWait. The returned
+// function releases the slot.
+func acquireVortexSlot() (func(), error) {
+	timer := time.NewTimer(vortexSlotWait)
+	defer timer.Stop()
+
+	select {
+	case vortexSlots <- struct{}{}:
+		return func() { <-vortexSlots }, nil
+	case <-timer.C:
+		return nil, errVortexBusy
+	}
+}
+
+// Run a Vortex SDK call within the concurrency limit, wrapping its error
+// with the matching sentinel
+func limitVortex[T any](call func() (T, error)) (T, error) {
+	release, err := acquireVortexSlot()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+	result, err := call()
+	return result, wrapVortexError(err)
+}
+
+// Run a Vortex SDK call that only returns an error within the concurrency limit
+func limitVortexErr(call func() error) error {
+	_, err := limitVortex(func() (struct{}, error) {
+		return struct{}{}, call()
+	})
+	return err
+}
+
+// CreateInvitationRequest is the payload for creating an invitation
+type CreateInvitationRequest struct {
+	Target   vortex.InvitationTarget `json:"target"`
+	Metadata map[string]interface{}  `json:"metadata,omitempty"`
+}
+
+// vortexStatusError is a non-2xx response from a direct Vortex API call
+type vortexStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *vortexStatusError) Error() string {
+	return fmt.Sprintf("vortex API error %d: %s", e.StatusCode, e.Body)
+}
+
+// vortexErrorClass categorizes a failed Vortex call
+type vortexErrorClass int
+
+const (
+	vortexErrUnknown     vortexErrorClass = iota
+	vortexErrUnreachable                  // network failure, timeout or upstream 5xx
+	vortexErrRejected                     // 4xx: Vortex rejected the request itself
+)
+
+// Sentinel errors for failed Vortex calls. Errors from the SDK and from
+// vortexRequest are wrapped with one of these where the failure is
+// recognized, so handlers can pick a status with errors.Is.
+var (
+	ErrNotFound     = errors.New("vortex: not found")
+	ErrUnauthorized = errors.New("vortex: unauthorized")
+	ErrRateLimited  = errors.New("vortex: rate limited")
+	ErrConflict     = errors.New("vortex: conflict")
+	ErrUpstream     = errors.New("vortex: upstream unavailable")
+)
+
+// HTTP status of a Vortex error response, or 0 when the call got no response
+func vortexErrorStatus(err error) int {
+	var apiErr *vortex.APIError
+	var statusErr *vortexStatusError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
+// Classify a Vortex error so handlers can tell an outage from a bad request
+func classifyVortexError(err error) vortexErrorClass {
+	status := vortexErrorStatus(err)
+	switch {
+	case status >= 500:
+		return vortexErrUnreachable
+	case status >= 400:
+		return vortexErrRejected
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return vortexErrUnreachable
+	}
+	return vortexErrUnknown
+}
+
+// Wrap a Vortex error with the sentinel it corresponds to, keeping the
+// original in the chain. Unrecognized errors are returned unchanged.
+func wrapVortexError(err error) error {
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) ||
+		errors.Is(err, ErrRateLimited) || errors.Is(err, ErrConflict) || errors.Is(err, ErrUpstream) {
+		return err
+	}
+
+	var sentinel error
+	switch status := vortexErrorStatus(err); {
+	case status == 404:
+		sentinel = ErrNotFound
+	case status == 401 || status == 403:
+		sentinel = ErrUnauthorized
+	case status == 429:
+		sentinel = ErrRateLimited
+	case status == 409:
+		sentinel = ErrConflict
+	case classifyVortexError(err) == vortexErrUnreachable:
+		sentinel = ErrUpstream
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
+
+// Whether err means Vortex couldn't serve the call (outage or saturated
+// concurrency limit) rather than answering it
+func isVortexUnavailable(err error) bool {
+	return errors.Is(err, errVortexBusy) || errors.Is(err, ErrUpstream)
+}
+
+// Respond 503 when Vortex is unreachable or the concurrency limit is
+// saturated. Returns false for other errors, which the caller reports itself.
+func respondIfUnavailable(c *gin.Context, err error) bool {
+	if errors.Is(err, errVortexBusy) {
+		respondErrorCause(c, 503, "upstream_busy", "Too many concurrent Vortex requests, please retry later", err)
+		return true
+	}
+	if !isVortexUnavailable(err) {
+		return false
+	}
+	respondErrorCause(c, 503, "upstream_unavailable", "Vortex is currently unreachable, please retry later", err)
+	return true
+}
+
+// Respond to a failed Vortex call with the status its sentinel maps to:
+// 503 for outages, 404 when Vortex doesn't know the resource, 409 when it
+// reports a conflict, 429 when Vortex is rate limiting us and 502 when it
+// rejects our API key. Anything else is a 500 with the given code and
+// message.
+func respondVortexError(c *gin.Context, err error, code, message string) {
+	if respondIfUnavailable(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondErrorCause(c, 404, "not_found", "Not found in Vortex", err)
+	case errors.Is(err, ErrConflict):
+		respondErrorCause(c, 409, "conflict", "Vortex reported a conflicting change", err)
+	case errors.Is(err, ErrRateLimited):
+		respondErrorCause(c, 429, "upstream_rate_limited", "Vortex is rate limiting requests, please retry later", err)
+	case errors.Is(err, ErrUnauthorized):
+		respondErrorCause(c, 502, "upstream_unauthorized", "Vortex rejected the server's credentials", err)
+	default:
+		respondErrorCause(c, 500, code, message, err)
+	}
+}
+
+// lastKnownCache remembers the latest successful read results so they can be
+// served, flagged as stale, while Vortex is unreachable
+type lastKnownCache struct {
+	mu      sync.RWMutex
+	entries map[string]interface{}
+}
+
+var lastKnown = &lastKnownCache{entries: make(map[string]interface{})}
+
+func (l *lastKnownCache) store(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[key] = value
+}
+
+func (l *lastKnownCache) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = make(map[string]interface{})
+}
+
+// Return the last known value for key, but only if err is an outage
+func (l *lastKnownCache) onOutage(key string, err error) (interface{}, bool) {
+	if !errors.Is(err, ErrUpstream) {
+		return nil, false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	value, ok := l.entries[key]
+	return value, ok
+}
+
+// invitationCache holds fetched invitations by ID for a short TTL so
+// repeated single-ID reads skip Vortex; writes through this server evict
+// the entries they touch
+type invitationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedInvitation
+}
+
+type cachedInvitation struct {
+	invitation *vortex.InvitationResult
+	expires    time.Time
+}
+
+var invitationsByID = &invitationCache{entries: make(map[string]cachedInvitation)}
+
+func (ic *invitationCache) get(id string) (*vortex.InvitationResult, bool) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	entry, ok := ic.entries[id]
+	if !ok || time.Now().After(entry.expires) {
+		delete(ic.entries, id)
+		return nil, false
+	}
+	return entry.invitation, true
+}
+
+func (ic *invitationCache) put(id string, invitation *vortex.InvitationResult) {
+	if ic.ttl <= 0 || invitation == nil {
+		return
+	}
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.entries[id] = cachedInvitation{invitation: invitation, expires: time.Now().Add(ic.ttl)}
+}
+
+func (ic *invitationCache) forget(ids ...string) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	for _, id := range ids {
+		delete(ic.entries, id)
+	}
+}
+
+func (ic *invitationCache) clear() {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.entries = make(map[string]cachedInvitation)
+}
+
+// Coalesces concurrent fetches of the same invitation into one Vortex call
+var invitationFetches singleflight.Group
+
+// Fetch an invitation from the cache or, on a miss, from Vortex, sharing
+// the upstream call with any concurrent requests for the same ID
+func fetchInvitation(id string) (*vortex.InvitationResult, error) {
+	if invitation, ok := invitationsByID.get(id); ok {
+		return invitation, nil
+	}
+
+	result, err, _ := invitationFetches.Do(id, func() (interface{}, error) {
+		return limitVortex(func() (*vortex.InvitationResult, error) {
+			return vortexInvitations.GetInvitation(id)
+		})
+	})
+	invitation, _ := result.(*vortex.InvitationResult)
+	if err == nil {
+		invitationsByID.put(id, invitation)
+	}
+	return invitation, err
+}
+
+// Invitation IDs are UUIDs or similar opaque tokens of letters, digits, '-'
+// and '_'; anything else can't be a Vortex ID and is rejected before the call
+var invitationIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,127}$`)
+
+const maxGroupParamLength = 128
+
+// Middleware rejecting malformed :id params on invitation routes
+func validInvitationID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !invitationIDPattern.MatchString(c.Param("id")) {
+			respondError(c, 400, "invalid_invitation_id", "Invitation ID is malformed")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Middleware rejecting empty or oversized :type/:id params on group routes
+func validGroupParams() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, name := range []string{"type", "id"} {
+			value := strings.TrimSpace(c.Param(name))
+			if value == "" || len(value) > maxGroupParamLength {
+				respondError(c, 400, "invalid_group_param", fmt.Sprintf("Group %s must be 1-%d characters", name, maxGroupParamLength))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Check a target type against allowedTargetTypes
+func isAllowedTargetType(targetType string) bool {
+	for _, t := range allowedTargetTypes {
+		if targetType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate that an invitation target has a supported type and a value
+func validateInvitationTarget(target vortex.InvitationTarget) error {
+	if strings.TrimSpace(target.Value) == "" {
+		return fmt.Errorf("target value is required")
+	}
+	if isAllowedTargetType(target.Type) {
+		return nil
+	}
+	return fmt.Errorf("unsupported target type %q (allowed: %s)", target.Type, strings.Join(allowedTargetTypes, ", "))
+}
+
+// Normalize a target value so lookups match regardless of how it was typed;
+// emails are compared case- and whitespace-insensitively, other values
+// whitespace-insensitively
+func normalizeTargetValue(targetType, value string) string {
+	if targetType == "email" {
+		return normalizeEmail(value)
+	}
+	return strings.TrimSpace(value)
+}
+
+// Longest non-email target value accepted in a target query
+const maxTargetValueLength = 256
+
+// Context key holding the normalized ?targetValue=
+const targetValueKey = "targetValue"
+
+// Middleware normalizing ?targetValue= for its ?targetType= and rejecting
+// oversized non-email values; the handler reads the result from the context
+func validTargetQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetType := c.Query("targetType")
+		targetValue := normalizeTargetValue(targetType, c.Query("targetValue"))
+		if targetType != "email" && len(targetValue) > maxTargetValueLength {
+			respondError(c, 400, "invalid_target_value", fmt.Sprintf("targetValue must be at most %d characters", maxTargetValueLength))
+			return
+		}
+		c.Set(targetValueKey, targetValue)
+		c.Next()
+	}
+}
+
+// Create an invitation via the Vortex API
+func createInvitation(ctx context.Context, req CreateInvitationRequest) (*vortex.InvitationResult, error) {
+	var invitation vortex.InvitationResult
+	if err := vortexRequest(ctx, "POST", "/api/v1/invitations", req, &invitation); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Merge metadata into an existing invitation via the Vortex API; the SDK
+// has no update method
+func updateInvitationMetadata(ctx context.Context, id string, metadata map[string]interface{}) (*vortex.InvitationResult, error) {
+	var invitation vortex.InvitationResult
+	body := map[string]interface{}{"metadata": metadata}
+	if err := vortexRequest(ctx, "PATCH", "/api/v1/invitations/"+url.PathEscape(id), body, &invitation); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Reinvite using a specific email template via the Vortex API; the SDK's
+// Reinvite takes no options
+func reinviteWithTemplate(ctx context.Context, id, template string) (*vortex.InvitationResult, error) {
+	var invitation vortex.InvitationResult
+	body := map[string]string{"template": template}
+	if err := vortexRequest(ctx, "POST", "/api/v1/invitations/"+url.PathEscape(id)+"/reinvite", body, &invitation); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Perform an authenticated JSON request against the Vortex API. The request
+// is abandoned when ctx is done, so callers pass the inbound request's
+// context to keep its deadline.
+func vortexRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, config.VortexBaseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", vortexAPIKey)
+
+	release, err := acquireVortexSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	resp, err := vortexHTTPClient.Do(req)
+	if err != nil {
+		return wrapVortexError(fmt.Errorf("vortex request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read vortex response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return wrapVortexError(&vortexStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))})
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode vortex response: %w", err)
+		}
+	}
+	return nil
+}
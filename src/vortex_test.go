@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	vortex "github.com/teamvortexsoftware/vortex-go-sdk"
+)
+
+// Point direct Vortex API calls at a test server, with the given number of
+// concurrency slots, for the duration of the test
+func useVortexServer(t *testing.T, slots int, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	useVortexSlots(t, slots)
+	useConfig(t, func(cfg *Config) { cfg.VortexBaseURL = server.URL })
+}
+
+func TestCreateInvitationForwardsToVortex(t *testing.T) {
+	var got CreateInvitationRequest
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v1/invitations" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(vortex.InvitationResult{ID: "inv-new", Status: "pending"})
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	payload := `{"target":{"type":"email","value":"new@example.com"},"metadata":{"source":"test"}}`
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations", strings.NewReader(payload)), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 201 {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+	if got.Target.Value != "new@example.com" || got.Metadata["source"] != "test" {
+		t.Fatalf("forwarded request = %+v", got)
+	}
+	var invitation vortex.InvitationResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &invitation); err != nil || invitation.ID != "inv-new" {
+		t.Fatalf("response = %s (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestCreateInvitationRejectsUnsupportedTarget(t *testing.T) {
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Vortex must not be called for an invalid target")
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	payload := `{"target":{"type":"fax","value":"555-0100"}}`
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations", strings.NewReader(payload)), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 400 || decodeErrorBody(t, rec).Code != "invalid_target" {
+		t.Fatalf("status = %d, body = %s, want 400 invalid_target", rec.Code, rec.Body.String())
+	}
+}
+
+func TestVortexHTTPClientHonorsTimeout(t *testing.T) {
+	release := make(chan struct{})
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) { <-release })
+	defer close(release)
+
+	saved := vortexHTTPClient
+	t.Cleanup(func() { vortexHTTPClient = saved })
+	vortexHTTPClient = newVortexHTTPClient(Config{VortexHTTPTimeout: 50 * time.Millisecond, VortexMaxIdleConns: 1})
+
+	start := time.Now()
+	_, err := createInvitation(context.Background(), CreateInvitationRequest{Target: vortex.InvitationTarget{Type: "email", Value: "a@example.com"}})
+	if err == nil {
+		t.Fatal("request to a hung server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("request took %s, timeout not applied", elapsed)
+	}
+}
+
+func TestVortexRequestStopsAtRequestDeadline(t *testing.T) {
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := vortexRequest(ctx, "GET", "/", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request ran %s past its deadline", elapsed)
+	}
+}
+
+func TestOutageServesStaleReadsAndFailsMutations(t *testing.T) {
+	saved := lastKnown
+	t.Cleanup(func() { lastKnown = saved })
+	lastKnown = &lastKnownCache{entries: make(map[string]interface{})}
+
+	outage := &vortex.APIError{StatusCode: 502, Message: "bad gateway"}
+	down := false
+	useInvitations(t, &fakeInvitations{
+		byGroup: func(string, string) ([]vortex.InvitationResult, error) {
+			if down {
+				return nil, outage
+			}
+			return []vortex.InvitationResult{{ID: "inv-1"}}, nil
+		},
+		reinvite: func(string) (*vortex.InvitationResult, error) { return nil, outage },
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+	list := func() *httptest.ResponseRecorder {
+		return serve(router, withSession(t, httptest.NewRequest("GET", "/api/vortex/invitations/by-group/team/team-1", nil), demoUsers[0]))
+	}
+
+	if rec := list(); rec.Code != 200 || strings.Contains(rec.Body.String(), "stale") {
+		t.Fatalf("healthy read: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	down = true
+	rec := list()
+	var body struct {
+		Invitations []vortex.InvitationResult `json:"invitations"`
+		Stale       bool                      `json:"stale"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if rec.Code != 200 || !body.Stale || len(body.Invitations) != 1 {
+		t.Fatalf("read during outage: status = %d, body = %s, want stale cached list", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(router, withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/inv-1/reinvite", nil), demoUsers[0]))
+	if rec.Code != 503 || decodeErrorBody(t, rec).Code != "upstream_unavailable" {
+		t.Fatalf("mutation during outage: status = %d, body = %s, want 503 upstream_unavailable", rec.Code, rec.Body.String())
+	}
+}
+
+func TestClassifyVortexError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want vortexErrorClass
+	}{
+		{&vortex.APIError{StatusCode: 503}, vortexErrUnreachable},
+		{&vortexStatusError{StatusCode: 404}, vortexErrRejected},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), vortexErrUnreachable},
+		{errors.New("boom"), vortexErrUnknown},
+	}
+	for _, tt := range tests {
+		if got := classifyVortexError(tt.err); got != tt.want {
+			t.Errorf("classify(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMalformedPathParamsRejectedBeforeVortex(t *testing.T) {
+	useInvitations(t, &fakeInvitations{
+		get: func(string) (*vortex.InvitationResult, error) {
+			t.Error("Vortex called with a malformed ID")
+			return nil, nil
+		},
+		byGroup: func(string, string) ([]vortex.InvitationResult, error) {
+			t.Error("Vortex called with a malformed group")
+			return nil, nil
+		},
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	tests := []struct {
+		path, code string
+	}{
+		{"/api/vortex/invitations/bad%20id", "invalid_invitation_id"},
+		{"/api/vortex/invitations/" + strings.Repeat("a", 129), "invalid_invitation_id"},
+		{"/api/vortex/invitations/by-group/team/%20", "invalid_group_param"},
+		{"/api/vortex/invitations/by-group/" + strings.Repeat("t", 129) + "/team-1", "invalid_group_param"},
+	}
+	for _, tt := range tests {
+		rec := serve(router, withSession(t, httptest.NewRequest("GET", tt.path, nil), demoUsers[0]))
+		if rec.Code != 400 || decodeErrorBody(t, rec).Code != tt.code {
+			t.Errorf("%s: status = %d, body = %s, want 400 %s", tt.path, rec.Code, rec.Body.String(), tt.code)
+		}
+	}
+}
+
+func TestFetchInvitationCoalescesConcurrentCalls(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	useInvitations(t, &fakeInvitations{get: func(id string) (*vortex.InvitationResult, error) {
+		calls.Add(1)
+		<-release
+		return &vortex.InvitationResult{ID: id}, nil
+	}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if invitation, err := fetchInvitation("inv-1"); err != nil || invitation.ID != "inv-1" {
+				t.Errorf("fetchInvitation = %v, %v", invitation, err)
+			}
+		}()
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream calls = %d, want 1", n)
+	}
+}
+
+func TestVortexRequestReportsBusyWhenSlotsAreTaken(t *testing.T) {
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {})
+	vortexSlots <- struct{}{}
+	defer func() { <-vortexSlots }()
+
+	if err := vortexRequest(context.Background(), "GET", "/", nil, nil); err != errVortexBusy {
+		t.Errorf("err = %v, want errVortexBusy", err)
+	}
+}
+
+func TestBusyVortexReturns503(t *testing.T) {
+	useInvitations(t, &fakeInvitations{})
+	useVortexSlots(t, 1)
+	vortexSlots <- struct{}{}
+	defer func() { <-vortexSlots }()
+
+	router := gin.New()
+	setupVortexRoutes(router)
+	req := withSession(t, httptest.NewRequest("DELETE", "/api/vortex/invitations/inv-1", nil), demoUsers[0])
+	rec := serve(router, req)
+	if rec.Code != 503 || decodeErrorBody(t, rec).Code != "upstream_busy" {
+		t.Errorf("status = %d, body = %s, want 503 upstream_busy", rec.Code, rec.Body)
+	}
+}
+
+func TestUpdateInvitationMetadataPatchesVortex(t *testing.T) {
+	var got map[string]interface{}
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/api/v1/invitations/inv-1" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(vortex.InvitationResult{ID: "inv-1"})
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	req := withSession(t, httptest.NewRequest("PATCH", "/api/vortex/invitations/inv-1", strings.NewReader(`{"team":"blue"}`)), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 200 {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if metadata, _ := got["metadata"].(map[string]interface{}); metadata["team"] != "blue" {
+		t.Errorf("forwarded body = %v", got)
+	}
+
+	req = withSession(t, httptest.NewRequest("PATCH", "/api/vortex/invitations/inv-1", strings.NewReader(`{}`)), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 400 || decodeErrorBody(t, rec).Code != "empty_metadata" {
+		t.Errorf("empty body: status = %d, body = %s, want 400 empty_metadata", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateInvitationNormalizesEmailTarget(t *testing.T) {
+	var got CreateInvitationRequest
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(vortex.InvitationResult{ID: "inv-new"})
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	payload := `{"target":{"type":"email","value":"  New.User@Example.COM "}}`
+	req := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations", strings.NewReader(payload)), demoUsers[0])
+	if rec := serve(router, req); rec.Code != 201 {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got.Target.Value != "new.user@example.com" {
+		t.Errorf("forwarded target = %q, want new.user@example.com", got.Target.Value)
+	}
+}
+
+func TestTemplatedReinviteTakesOneSlot(t *testing.T) {
+	var got map[string]string
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/invitations/inv-1/reinvite" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(vortex.InvitationResult{ID: "inv-1", Status: "pending"})
+	})
+	config.ReinviteCooldown = 0
+	router := gin.New()
+	router.POST("/invitations/:id/reinvite", reinviteHandler)
+
+	start := time.Now()
+	rec := serve(router, httptest.NewRequest("POST", "/invitations/inv-1/reinvite?template=reminder", nil))
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if elapsed := time.Since(start); elapsed >= vortexSlotWait {
+		t.Errorf("reinvite waited %s for a slot", elapsed)
+	}
+	if got["template"] != "reminder" {
+		t.Errorf("forwarded body = %v, want template reminder", got)
+	}
+	if len(vortexSlots) != 0 {
+		t.Errorf("%d slots still held after the request", len(vortexSlots))
+	}
+}
+
+func TestUnknownReinviteTemplateListsAllowed(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.ReinviteTemplates = []string{"default", "reminder"} })
+	router := gin.New()
+	router.POST("/invitations/:id/reinvite", reinviteHandler)
+
+	rec := serve(router, httptest.NewRequest("POST", "/invitations/inv-1/reinvite?template=shouty", nil))
+	body := decodeErrorBody(t, rec)
+	if rec.Code != 400 || body.Code != "invalid_template" || strings.Join(body.Allowed, ",") != "default,reminder" {
+		t.Errorf("status = %d, body = %s, want 400 invalid_template with allowed", rec.Code, rec.Body)
+	}
+}
+
+func TestPrewarmSeedsInvitationCache(t *testing.T) {
+	useConfig(t, nil)
+	gets := 0
+	useInvitations(t, &fakeInvitations{
+		byGroup: func(groupType, groupID string) ([]vortex.InvitationResult, error) {
+			return []vortex.InvitationResult{{ID: "inv-1", Status: "pending"}}, nil
+		},
+		get: func(id string) (*vortex.InvitationResult, error) {
+			gets++
+			return &vortex.InvitationResult{ID: id}, nil
+		},
+	})
+	invitationsByID.ttl = time.Minute
+	t.Cleanup(func() {
+		invitationsByID.ttl = 0
+		invitationsByID.clear()
+	})
+	router := gin.New()
+	setupVortexRoutes(router)
+
+	prewarm := withSession(t, httptest.NewRequest("POST", "/api/vortex/invitations/by-group/team/team-1/prewarm", nil), demoUsers[0])
+	if rec := serve(router, prewarm); rec.Code != 200 || !strings.Contains(rec.Body.String(), `"warmed":1`) {
+		t.Fatalf("prewarm: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if _, err := fetchInvitation("inv-1"); err != nil || gets != 0 {
+		t.Errorf("prewarmed fetch: err = %v, upstream gets = %d, want 0", err, gets)
+	}
+
+	invitationsByID.forget("inv-1")
+	if _, err := fetchInvitation("inv-1"); err != nil || gets != 1 {
+		t.Errorf("after forget: err = %v, upstream gets = %d, want 1", err, gets)
+	}
+}
+
+func TestTargetQueryIsNormalizedAndLengthChecked(t *testing.T) {
+	var lookups []string
+	useInvitations(t, &fakeInvitations{byTarget: func(targetType, targetValue string) ([]vortex.InvitationResult, error) {
+		lookups = append(lookups, targetValue)
+		return nil, nil
+	}})
+	router := gin.New()
+	setupVortexRoutes(router)
+	list := func(query string) *httptest.ResponseRecorder {
+		return serve(router, withSession(t, httptest.NewRequest("GET", "/api/vortex/invitations?"+query, nil), demoUsers[0]))
+	}
+
+	list("targetType=email&targetValue=%20Foo@Bar.COM%20")
+	list("targetType=phoneNumber&targetValue=%20555-0100%20")
+	if len(lookups) != 2 || lookups[0] != "foo@bar.com" || lookups[1] != "555-0100" {
+		t.Errorf("lookups = %q, want [foo@bar.com 555-0100]", lookups)
+	}
+
+	rec := list("targetType=phoneNumber&targetValue=" + strings.Repeat("5", maxTargetValueLength+1))
+	if rec.Code != 400 || decodeErrorBody(t, rec).Code != "invalid_target_value" {
+		t.Errorf("oversized: status = %d, body = %s, want 400 invalid_target_value", rec.Code, rec.Body)
+	}
+	if len(lookups) != 2 {
+		t.Error("Vortex called with an oversized target value")
+	}
+}
+
+func TestVortexErrorsMapToHandlerStatuses(t *testing.T) {
+	useConfig(t, nil)
+	tests := []struct {
+		upstream int
+		status   int
+		code     string
+	}{
+		{404, 404, "not_found"},
+		{429, 429, "upstream_rate_limited"},
+		{401, 502, "upstream_unauthorized"},
+		{403, 502, "upstream_unauthorized"},
+		{503, 503, "upstream_unavailable"},
+		{400, 500, "revoke_failed"},
+	}
+	for _, tt := range tests {
+		useInvitations(t, &fakeInvitations{revoke: func(string) error {
+			return &vortex.APIError{StatusCode: tt.upstream}
+		}})
+		router := gin.New()
+		setupVortexRoutes(router)
+
+		rec := serve(router, withSession(t, httptest.NewRequest("DELETE", "/api/vortex/invitations/inv-1", nil), demoUsers[0]))
+		if rec.Code != tt.status || decodeErrorBody(t, rec).Code != tt.code {
+			t.Errorf("Vortex %d: status = %d, body = %s, want %d %s", tt.upstream, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+}
+
+func TestDirectVortexCallsWrapSentinels(t *testing.T) {
+	useVortexServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such invitation", http.StatusNotFound)
+	})
+
+	err := vortexRequest(context.Background(), "GET", "/api/v1/invitations/missing", nil, nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	var statusErr *vortexStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 404 {
+		t.Errorf("original status error lost from chain: %v", err)
+	}
+}